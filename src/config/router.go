@@ -9,12 +9,20 @@ import (
 func AddRoutes(app *iris.Application) {
 	app.Get("/palette", controllers.GetPalette)
 
+	app.Get("/animate/zoom", controllers.GetZoomAnimation)
+	app.Get("/attractor", controllers.GetAttractor)
 	app.Get("/cantor-dust", controllers.GetCantorDust)
 	app.Get("/cantor-set", controllers.GetCantorSet)
+	app.Get("/flame", controllers.GetFlame)
 	app.Get("/hopalong", controllers.GetHopalong)
 	app.Get("/ifs", controllers.GetIFS)
 	app.Get("/julia-set", controllers.GetJuliaSet)
+	app.Get("/julia-set/parameter-space", controllers.GetJuliaSetParameterSpace)
 	app.Get("/l-system", controllers.GetLindenmayerSystem)
+	app.Get("/l-system/presets", controllers.GetLindenmayerSystemPresets)
+	app.Get("/library", controllers.GetLibrary)
+	app.Post("/library", controllers.PostLibrary)
+	app.Get("/lyapunov", controllers.GetLyapunov)
 	app.Get("/mandelbrot-set", controllers.GetMandelbrotSet)
 	app.Get("/newton-basin", controllers.GetNewtonBasin)
 	app.Get("/sierpinski-carpet", controllers.GetSierpinskiCarpet)
@@ -65,9 +65,15 @@ func GetPalette(ctx iris.Context) {
 	if colorPalette.Transitions != nil {
 		step = float64(width) / float64((len(colorPalette.Transitions) - 1) * divisions)
 	}
-	ctx.ContentType("image/png")
-	err = colorPalette.Render(ctx.ResponseWriter(), width, height, step)
+	output, err := getOutputOptions(ctx, helpers.RASTER_FORMATS)
 	if err != nil {
 		ctx.Text(err.Error())
+		return
+	}
+	img, err := colorPalette.CreateImage(width, height, step, output.BitDepth())
+	if err != nil {
+		ctx.Text(err.Error())
+		return
 	}
+	writeImage(ctx, output, img)
 }
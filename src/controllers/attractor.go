@@ -0,0 +1,175 @@
+package controllers
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+
+	"github.com/B3zaleel/fractage/src/fractals"
+	"github.com/B3zaleel/fractage/src/helpers"
+	"github.com/kataras/iris/v12"
+)
+
+const (
+	ATTRACTOR_MAX_MARGIN = 1_000
+)
+
+func GetAttractor(ctx iris.Context) {
+	query := ctx.Request().URL.Query()
+	fractal := fractals.Attractor{
+		Width:       DEFAULT_WIDTH,
+		Height:      DEFAULT_HEIGHT,
+		Type:        fractals.ATTRACTOR_DEFAULT_TYPE,
+		Iterations:  fractals.ATTRACTOR_DEFAULT_ITERATIONS,
+		ToneMapping: fractals.ATTRACTOR_DEFAULT_TONE_MAPPING,
+		Gamma:       fractals.ATTRACTOR_DEFAULT_GAMMA,
+		Margin:      fractals.ATTRACTOR_DEFAULT_MARGIN,
+		Background:  color.RGBA{0, 0, 0, 255},
+	}
+	colorPaletteValue := fractals.ATTRACTOR_DEFAULT_COLOR_PALETTE
+	if query.Has("width") {
+		width, err := strconv.Atoi(query.Get("width"))
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		fractal.Width = width
+	}
+	if query.Has("height") {
+		height, err := strconv.Atoi(query.Get("height"))
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		fractal.Height = height
+	}
+	if query.Has("type") {
+		attractorType := query.Get("type")
+		if !fractals.IsValidAttractorType(attractorType) {
+			ctx.Text("Invalid attractor type")
+			return
+		}
+		fractal.Type = strings.Trim(attractorType, helpers.WHITESPACE_CUTSET)
+	}
+	attractorType := fractals.ATTRACTOR_TYPES[fractal.Type]
+	fractal.Parameters = make([]float64, len(attractorType.Parameters))
+	copy(fractal.Parameters, attractorType.Defaults)
+	for i, name := range attractorType.Parameters {
+		if query.Has(name) {
+			value, err := strconv.ParseFloat(query.Get(name), 64)
+			if err != nil {
+				ctx.Text(err.Error())
+				return
+			}
+			fractal.Parameters[i] = value
+		}
+	}
+	fractal.X = attractorType.Start.X
+	fractal.Y = attractorType.Start.Y
+	if query.Has("x") {
+		x, err := strconv.ParseFloat(query.Get("x"), 64)
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		fractal.X = x
+	}
+	if query.Has("y") {
+		y, err := strconv.ParseFloat(query.Get("y"), 64)
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		fractal.Y = y
+	}
+	if query.Has("iterations") {
+		iterations, err := strconv.Atoi(query.Get("iterations"))
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		if iterations < 0 || iterations > fractals.ATTRACTOR_MAX_ITERATIONS {
+			ctx.Text(fmt.Sprintf("Too many iterations. Max: %d\n", fractals.ATTRACTOR_MAX_ITERATIONS))
+			return
+		}
+		fractal.Iterations = iterations
+	}
+	if query.Has("color_palette") {
+		colorPaletteValue = query.Get("color_palette")
+	}
+	if query.Has("tone_mapping") {
+		toneMapping := query.Get("tone_mapping")
+		if !helpers.IsValidToneMapping(toneMapping) {
+			ctx.Text("Invalid tone mapping")
+			return
+		}
+		fractal.ToneMapping = toneMapping
+	}
+	if query.Has("gamma") {
+		gamma, err := strconv.ParseFloat(query.Get("gamma"), 64)
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		if gamma <= 0 {
+			ctx.Text("gamma must be greater than 0")
+			return
+		}
+		fractal.Gamma = gamma
+	}
+	if query.Has("margin") {
+		margin, err := strconv.ParseFloat(query.Get("margin"), 64)
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		if margin < 0 || margin > ATTRACTOR_MAX_MARGIN {
+			ctx.Text(fmt.Sprintf("margin must be between 0 and %d\n", ATTRACTOR_MAX_MARGIN))
+			return
+		}
+		fractal.Margin = margin
+	}
+	if query.Has("symmetry") {
+		symmetry, err := helpers.ParseSymmetry(query.Get("symmetry"))
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		fractal.Symmetry = symmetry
+	}
+	if query.Has("symmetry_center") {
+		center, err := helpers.ParsePoint(query.Get("symmetry_center"))
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		fractal.SymmetryCenter = center
+	}
+	if query.Has("background") {
+		background, err := helpers.ParseColor(query.Get("background"))
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		fractal.Background = background
+	}
+	colorPalette, err := helpers.ParseColorPalette(colorPaletteValue)
+	if err != nil {
+		ctx.Text(err.Error())
+		return
+	}
+	fractal.ColorPalette = colorPalette
+	output, err := getOutputOptions(ctx, helpers.RASTER_FORMATS)
+	if err != nil {
+		ctx.Text(err.Error())
+		return
+	}
+	fractal.BitDepth = output.BitDepth()
+	img, err := fractal.CreateImage()
+	if err != nil {
+		ctx.Text(err.Error())
+		return
+	}
+	writeImage(ctx, output, img)
+}
@@ -82,6 +82,10 @@ func GetCantorSet(ctx iris.Context) {
 		}
 		fractal.Background = background
 	}
-	ctx.ContentType("image/png")
-	fractal.WriteImage(ctx.ResponseWriter())
+	output, err := getOutputOptions(ctx, helpers.VECTOR_FORMATS)
+	if err != nil {
+		ctx.Text(err.Error())
+		return
+	}
+	writeVectorImage(ctx, output, &fractal)
 }
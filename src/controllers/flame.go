@@ -0,0 +1,241 @@
+package controllers
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+
+	"github.com/B3zaleel/fractage/src/fractals"
+	"github.com/B3zaleel/fractage/src/helpers"
+	"github.com/kataras/iris/v12"
+)
+
+const (
+	FLAME_MAX_MARGIN = 1_000
+)
+
+func GetFlame(ctx iris.Context) {
+	query := ctx.Request().URL.Query()
+	fractal := fractals.Flame{
+		Width:           DEFAULT_WIDTH,
+		Height:          DEFAULT_HEIGHT,
+		Iterations:      fractals.FLAME_DEFAULT_ITERATIONS,
+		Supersample:     fractals.FLAME_DEFAULT_SUPERSAMPLE,
+		EstimatorRadius: fractals.FLAME_DEFAULT_ESTIMATOR_RADIUS,
+		EstimatorCurve:  fractals.FLAME_DEFAULT_ESTIMATOR_CURVE,
+		Gamma:           fractals.FLAME_DEFAULT_GAMMA,
+		Vibrancy:        fractals.FLAME_DEFAULT_VIBRANCY,
+		Brightness:      fractals.FLAME_DEFAULT_BRIGHTNESS,
+		Focus:           true,
+		Margin:          fractals.FLAME_DEFAULT_MARGIN,
+		Background:      color.RGBA{0, 0, 0, 255},
+	}
+	transformsValue := fractals.FLAME_DEFAULT_TRANSFORMS
+	colorPaletteValue := fractals.FLAME_DEFAULT_COLOR_PALETTE
+	if query.Has("width") {
+		width, err := strconv.Atoi(query.Get("width"))
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		fractal.Width = width
+	}
+	if query.Has("height") {
+		height, err := strconv.Atoi(query.Get("height"))
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		fractal.Height = height
+	}
+	if query.Has("transforms") && query.Has("name") {
+		ctx.Text("Only one of transforms or name can be given")
+		return
+	}
+	if query.Has("transforms") {
+		transformsValue = query.Get("transforms")
+	}
+	if query.Has("name") {
+		library, err := fractals.LoadLibrary()
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		entry, err := library.FindFlame(query.Get("name"))
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		fractal.Transforms = entry.Transforms
+		fractal.Final = entry.Final
+		fractal.Gamma = entry.Gamma
+		fractal.Vibrancy = entry.Vibrancy
+		fractal.Background = entry.Background
+		if entry.Region.Width > 0 && entry.Region.Height > 0 {
+			fractal.Region = entry.Region
+			fractal.Focus = false
+		}
+		if len(entry.ColorPalette.Transitions) > 0 {
+			fractal.ColorPalette = entry.ColorPalette.Copy()
+		}
+	} else {
+		transforms, err := fractals.ParseFlameTransforms(transformsValue)
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		fractal.Transforms = transforms
+	}
+	if query.Has("final") {
+		final, err := fractals.ParseFlameFinalTransform(query.Get("final"))
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		fractal.Final = &final
+	}
+	if query.Has("iterations") {
+		iterations, err := strconv.Atoi(query.Get("iterations"))
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		if iterations < 0 || iterations > fractals.FLAME_MAX_ITERATIONS {
+			ctx.Text(fmt.Sprintf("Too many iterations. Max: %d\n", fractals.FLAME_MAX_ITERATIONS))
+			return
+		}
+		fractal.Iterations = iterations
+	}
+	if query.Has("seed") {
+		seed, err := strconv.ParseInt(query.Get("seed"), 10, 64)
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		fractal.Seed = seed
+	}
+	if query.Has("supersample") {
+		supersample, err := strconv.Atoi(query.Get("supersample"))
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		if supersample < 1 || supersample > fractals.FLAME_MAX_SUPERSAMPLE {
+			ctx.Text(fmt.Sprintf("supersample must be between 1 and %d\n", fractals.FLAME_MAX_SUPERSAMPLE))
+			return
+		}
+		fractal.Supersample = supersample
+	}
+	if query.Has("estimator_radius") {
+		radius, err := strconv.ParseFloat(query.Get("estimator_radius"), 64)
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		if radius < 0 || radius > fractals.FLAME_MAX_ESTIMATOR_RADIUS {
+			ctx.Text(fmt.Sprintf("estimator_radius must be between 0 and %d\n", fractals.FLAME_MAX_ESTIMATOR_RADIUS))
+			return
+		}
+		fractal.EstimatorRadius = radius
+	}
+	if query.Has("estimator_curve") {
+		curve, err := strconv.ParseFloat(query.Get("estimator_curve"), 64)
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		if curve <= 0 {
+			ctx.Text("estimator_curve must be greater than 0")
+			return
+		}
+		fractal.EstimatorCurve = curve
+	}
+	if query.Has("gamma") {
+		gamma, err := strconv.ParseFloat(query.Get("gamma"), 64)
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		if gamma <= 0 {
+			ctx.Text("gamma must be greater than 0")
+			return
+		}
+		fractal.Gamma = gamma
+	}
+	if query.Has("vibrancy") {
+		vibrancy, err := strconv.ParseFloat(query.Get("vibrancy"), 64)
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		if vibrancy < 0 || vibrancy > 1 {
+			ctx.Text("vibrancy must be between 0 and 1")
+			return
+		}
+		fractal.Vibrancy = vibrancy
+	}
+	if query.Has("brightness") {
+		brightness, err := strconv.ParseFloat(query.Get("brightness"), 64)
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		if brightness <= 0 {
+			ctx.Text("brightness must be greater than 0")
+			return
+		}
+		fractal.Brightness = brightness
+	}
+	if query.Has("region") {
+		region, err := helpers.ParseRect(query.Get("region"))
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		fractal.Region = region
+		fractal.Focus = false
+	}
+	if query.Has("margin") {
+		margin, err := strconv.ParseFloat(query.Get("margin"), 64)
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		if margin < 0 || margin > FLAME_MAX_MARGIN {
+			ctx.Text(fmt.Sprintf("margin must be between 0 and %d\n", FLAME_MAX_MARGIN))
+			return
+		}
+		fractal.Margin = margin
+	}
+	if query.Has("color_palette") {
+		colorPaletteValue = query.Get("color_palette")
+	}
+	if query.Has("background") {
+		background, err := helpers.ParseColor(query.Get("background"))
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		fractal.Background = background
+	}
+	if query.Has("color_palette") || len(fractal.ColorPalette.Transitions) == 0 {
+		colorPalette, err := helpers.ParseColorPalette(colorPaletteValue)
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		fractal.ColorPalette = colorPalette
+	}
+	output, err := getOutputOptions(ctx, helpers.RASTER_FORMATS)
+	if err != nil {
+		ctx.Text(err.Error())
+		return
+	}
+	fractal.BitDepth = output.BitDepth()
+	img, err := fractal.CreateImage()
+	if err != nil {
+		ctx.Text(err.Error())
+		return
+	}
+	writeImage(ctx, output, img)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"image/color"
 	"strconv"
+	"strings"
 
 	"github.com/B3zaleel/fractage/src/fractals"
 	"github.com/B3zaleel/fractage/src/helpers"
@@ -13,14 +14,15 @@ import (
 const (
 	HOPALONG_MAX_RESOLUTION     = 5_000
 	HOPALONG_DEFAULT_RESOLUTION = 5
-	HOPALONG_DEFAULT_A          = 5
-	HOPALONG_DEFAULT_B          = 1
-	HOPALONG_DEFAULT_C          = 5
-	HOPALONG_DEFAULT_D          = 0
-	HOPALONG_DEFAULT_X          = -1
-	HOPALONG_DEFAULT_Y          = 0
 	HOPALONG_DEFAULT_Scale      = 5
 	HOPALONG_DEFAULT_FXN_TYPE   = "classic_bm"
+	HOPALONG_MAX_ITERATIONS     = 100_000_000
+	HOPALONG_DEFAULT_FOCUS      = false
+	HOPALONG_DEFAULT_MARGIN     = 10
+	HOPALONG_MAX_MARGIN         = 1_000
+	HOPALONG_DEFAULT_COLORING   = fractals.HOPALONG_COLORING_SOLID
+	HOPALONG_DEFAULT_PALETTE    = "fire"
+	HOPALONG_DEFAULT_GAMMA      = 2.2
 )
 
 func GetHopalong(ctx iris.Context) {
@@ -28,18 +30,18 @@ func GetHopalong(ctx iris.Context) {
 	fractal := fractals.Hopalong{
 		Width:           DEFAULT_WIDTH,
 		Height:          DEFAULT_HEIGHT,
-		A:               HOPALONG_DEFAULT_A,
-		B:               HOPALONG_DEFAULT_B,
-		C:               HOPALONG_DEFAULT_C,
-		D:               HOPALONG_DEFAULT_D,
-		X:               HOPALONG_DEFAULT_X,
-		Y:               HOPALONG_DEFAULT_Y,
 		Type:            HOPALONG_DEFAULT_FXN_TYPE,
 		Scale:           HOPALONG_DEFAULT_Scale,
 		UseRandomColors: true,
 		Resolution:      HOPALONG_DEFAULT_RESOLUTION,
+		Focus:           HOPALONG_DEFAULT_FOCUS,
+		Margin:          HOPALONG_DEFAULT_MARGIN,
+		Coloring:        HOPALONG_DEFAULT_COLORING,
+		ToneMapping:     helpers.TONE_MAPPING_LOG,
+		Gamma:           HOPALONG_DEFAULT_GAMMA,
 		Background:      color.RGBA{255, 255, 255, 255},
 	}
+	colorPaletteValue := HOPALONG_DEFAULT_PALETTE
 	if query.Has("width") {
 		width, err := strconv.Atoi(query.Get("width"))
 		if err != nil {
@@ -56,6 +58,14 @@ func GetHopalong(ctx iris.Context) {
 		}
 		fractal.Height = height
 	}
+	if query.Has("type") {
+		fxnType := query.Get("type")
+		if !fractals.IsValidHopalongType(fxnType) {
+			ctx.Text(fmt.Sprintf("Invalid hopalong type: %s\n", fxnType))
+			return
+		}
+		fractal.Type = strings.Trim(fxnType, helpers.WHITESPACE_CUTSET)
+	}
 	if query.Has("color") {
 		color, err := helpers.ParseColor(query.Get("color"))
 		if err != nil {
@@ -77,76 +87,122 @@ func GetHopalong(ctx iris.Context) {
 		}
 		fractal.Resolution = resolution
 	}
-	if query.Has("a") {
-		a, err := strconv.ParseFloat(query.Get("a"), 32)
+	if query.Has("iterations") {
+		iterations, err := strconv.Atoi(query.Get("iterations"))
 		if err != nil {
 			ctx.Text(err.Error())
 			return
 		}
-		fractal.A = a
+		if iterations < 0 || iterations > HOPALONG_MAX_ITERATIONS {
+			ctx.Text(fmt.Sprintf("Too many iterations. Max: %d\n", HOPALONG_MAX_ITERATIONS))
+			return
+		}
+		fractal.Iterations = iterations
 	}
-	if query.Has("b") {
-		b, err := strconv.ParseFloat(query.Get("b"), 32)
+	hopalongType := fractals.HOPALONG_TYPES[fractal.Type]
+	for i, name := range hopalongType.Parameters {
+		*fractal.Parameter(name) = hopalongType.Defaults[i]
+		if query.Has(name) {
+			value, err := strconv.ParseFloat(query.Get(name), 64)
+			if err != nil {
+				ctx.Text(err.Error())
+				return
+			}
+			*fractal.Parameter(name) = value
+		}
+	}
+	fractal.X = hopalongType.Start.X
+	fractal.Y = hopalongType.Start.Y
+	if query.Has("x") {
+		x, err := strconv.ParseFloat(query.Get("x"), 32)
 		if err != nil {
 			ctx.Text(err.Error())
 			return
 		}
-		fractal.B = b
+		fractal.X = x
 	}
-	if query.Has("c") {
-		c, err := strconv.ParseFloat(query.Get("c"), 32)
+	if query.Has("y") {
+		y, err := strconv.ParseFloat(query.Get("y"), 32)
 		if err != nil {
 			ctx.Text(err.Error())
 			return
 		}
-		fractal.C = c
+		fractal.Y = y
 	}
-	if query.Has("d") {
-		d, err := strconv.ParseFloat(query.Get("d"), 32)
+	if query.Has("scale") {
+		scale, err := strconv.ParseFloat(query.Get("scale"), 32)
 		if err != nil {
 			ctx.Text(err.Error())
 			return
 		}
-		fractal.D = d
+		fractal.Scale = scale
 	}
-	if query.Has("x") {
-		x, err := strconv.ParseFloat(query.Get("x"), 32)
+	if query.Has("focus") {
+		focus, err := strconv.ParseBool(query.Get("focus"))
 		if err != nil {
 			ctx.Text(err.Error())
 			return
 		}
-		fractal.X = x
+		fractal.Focus = focus
 	}
-	if query.Has("y") {
-		y, err := strconv.ParseFloat(query.Get("y"), 32)
+	if query.Has("margin") {
+		margin, err := strconv.ParseFloat(query.Get("margin"), 64)
 		if err != nil {
 			ctx.Text(err.Error())
 			return
 		}
-		fractal.Y = y
+		if margin < 0 || margin > HOPALONG_MAX_MARGIN {
+			ctx.Text(fmt.Sprintf("margin must be between 0 and %d\n", HOPALONG_MAX_MARGIN))
+			return
+		}
+		fractal.Margin = margin
 	}
-	if query.Has("scale") {
-		scale, err := strconv.ParseFloat(query.Get("scale"), 32)
+	if query.Has("coloring") {
+		coloring := query.Get("coloring")
+		if !fractals.IsValidHopalongColoring(coloring) {
+			ctx.Text("Invalid coloring")
+			return
+		}
+		fractal.Coloring = coloring
+	}
+	if query.Has("color_palette") {
+		colorPaletteValue = query.Get("color_palette")
+	}
+	if query.Has("tone_mapping") {
+		toneMapping := query.Get("tone_mapping")
+		if !helpers.IsValidToneMapping(toneMapping) {
+			ctx.Text("Invalid tone mapping")
+			return
+		}
+		fractal.ToneMapping = toneMapping
+	}
+	if query.Has("gamma") {
+		gamma, err := strconv.ParseFloat(query.Get("gamma"), 64)
 		if err != nil {
 			ctx.Text(err.Error())
 			return
 		}
-		fractal.Scale = scale
+		if gamma <= 0 {
+			ctx.Text("gamma must be greater than 0")
+			return
+		}
+		fractal.Gamma = gamma
 	}
-	if query.Has("type") {
-		fxnType := query.Get("type")
-		validFxn := false
-		for k := range fractals.HOPALONG_TYPES {
-			if k == fxnType {
-				validFxn = true
-				break
-			}
+	if query.Has("symmetry") {
+		symmetry, err := helpers.ParseSymmetry(query.Get("symmetry"))
+		if err != nil {
+			ctx.Text(err.Error())
+			return
 		}
-		if !validFxn {
-			ctx.Text("Invalid function type")
+		fractal.Symmetry = symmetry
+	}
+	if query.Has("symmetry_center") {
+		center, err := helpers.ParsePoint(query.Get("symmetry_center"))
+		if err != nil {
+			ctx.Text(err.Error())
 			return
 		}
-		fractal.Type = fxnType
+		fractal.SymmetryCenter = center
 	}
 	if query.Has("background") {
 		background, err := helpers.ParseColor(query.Get("background"))
@@ -156,6 +212,24 @@ func GetHopalong(ctx iris.Context) {
 		}
 		fractal.Background = background
 	}
-	ctx.ContentType("image/png")
-	fractal.WriteImage(ctx.ResponseWriter())
+	if fractal.Coloring != fractals.HOPALONG_COLORING_SOLID {
+		colorPalette, err := helpers.ParseColorPalette(colorPaletteValue)
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		fractal.ColorPalette = colorPalette
+	}
+	output, err := getOutputOptions(ctx, helpers.RASTER_FORMATS)
+	if err != nil {
+		ctx.Text(err.Error())
+		return
+	}
+	fractal.BitDepth = output.BitDepth()
+	img, err := fractal.CreateImage()
+	if err != nil {
+		ctx.Text(err.Error())
+		return
+	}
+	writeImage(ctx, output, img)
 }
@@ -0,0 +1,14 @@
+package controllers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetHopalongUnusedParameters(t *testing.T) {
+	target := "/hopalong?width=64&height=64&iterations=1000&a=1&b=2&c=3&d=4"
+	recorder := serveTestRequest(t, "/hopalong", GetHopalong, target, nil)
+	if contentType := recorder.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "image/png") {
+		t.Fatalf("got content type %q and body %q, want a PNG image", contentType, recorder.Body.String())
+	}
+}
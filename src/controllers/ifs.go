@@ -22,19 +22,26 @@ const (
 	IFS_DEFAULT_SYSTEM_VARIABLES = "0.0,0.0,0.0,0.16,0.0,0.0,0.01, 0.2,-0.26,0.23,0.22,0.0,1.6,0.07, -0.15,0.28,0.26,0.24,0.0,0.44,0.07, 0.85,0.04,-0.04,0.85,0.0,1.6,0.85"
 	IFS_DEFAULT_SYSTEM_COLORS    = "mahogany, mahogany, mahogany, mahogany"
 	IFS_DEFAULT_FOCUS            = true
+	IFS_DEFAULT_METHOD           = fractals.IFS_METHOD_CHAOS
+	IFS_DEFAULT_GENERATIONS      = 30
+	IFS_MAX_GENERATIONS          = 1_000
+	IFS_DEFAULT_SHAPE            = fractals.IFS_SHAPE_SQUARE
 )
 
 func GetIFS(ctx iris.Context) {
 	query := ctx.Request().URL.Query()
 	fractal := fractals.IteratedFunctionSystem{
-		Width:      DEFAULT_WIDTH,
-		Height:     DEFAULT_HEIGHT,
-		Iterations: IFS_DEFAULT_ITERATIONS,
-		X:          IFS_DEFAULT_X,
-		Y:          IFS_DEFAULT_Y,
-		Focus:      IFS_DEFAULT_FOCUS,
-		Scale:      IFS_DEFAULT_SCALE,
-		Background: color.RGBA{255, 255, 255, 255},
+		Width:       DEFAULT_WIDTH,
+		Height:      DEFAULT_HEIGHT,
+		Iterations:  IFS_DEFAULT_ITERATIONS,
+		X:           IFS_DEFAULT_X,
+		Y:           IFS_DEFAULT_Y,
+		Focus:       IFS_DEFAULT_FOCUS,
+		Scale:       IFS_DEFAULT_SCALE,
+		Method:      IFS_DEFAULT_METHOD,
+		Generations: IFS_DEFAULT_GENERATIONS,
+		Shape:       IFS_DEFAULT_SHAPE,
+		Background:  color.RGBA{255, 255, 255, 255},
 	}
 	ifsVariables := IFS_DEFAULT_SYSTEM_VARIABLES
 	ifsColors := IFS_DEFAULT_SYSTEM_COLORS
@@ -54,10 +61,34 @@ func GetIFS(ctx iris.Context) {
 		}
 		fractal.Height = height
 	}
+	sources := 0
+	for _, name := range []string{"variables", "transforms", "name"} {
+		if query.Has(name) {
+			sources++
+		}
+	}
+	if sources > 1 {
+		ctx.Text("Only one of variables, transforms or name can be given")
+		return
+	}
 	if query.Has("variables") {
 		ifsVariables = query.Get("variables")
 	}
-	variables, err := fractals.GetIFSVariables(ifsVariables)
+	var variables [][fractals.IFS_FXN_VARIABLES_COUNT]float64
+	var err error
+	if query.Has("transforms") {
+		variables, err = fractals.ParseIFSTransforms(query.Get("transforms"))
+	} else if query.Has("name") {
+		var library fractals.Library
+		var entry fractals.IFSLibraryEntry
+		library, err = fractals.LoadLibrary()
+		if err == nil {
+			entry, err = library.FindIFS(query.Get("name"))
+			variables = entry.Variables
+		}
+	} else {
+		variables, err = fractals.GetIFSVariables(ifsVariables)
+	}
 	if err != nil {
 		ctx.Text(err.Error())
 		return
@@ -122,6 +153,50 @@ func GetIFS(ctx iris.Context) {
 		}
 		fractal.Focus = focus
 	}
+	if query.Has("method") {
+		method := query.Get("method")
+		if !fractals.IsValidIFSMethod(method) {
+			ctx.Text("Invalid method")
+			return
+		}
+		fractal.Method = method
+	}
+	if query.Has("generations") {
+		generations, err := strconv.Atoi(query.Get("generations"))
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		if generations < 0 || generations > IFS_MAX_GENERATIONS {
+			ctx.Text(fmt.Sprintf("generations must be between 0 and %d\n", IFS_MAX_GENERATIONS))
+			return
+		}
+		fractal.Generations = generations
+	}
+	if query.Has("shape") {
+		shape := query.Get("shape")
+		if !fractals.IsValidIFSShape(shape) {
+			ctx.Text("Invalid shape")
+			return
+		}
+		fractal.Shape = shape
+	}
+	if query.Has("symmetry") {
+		symmetry, err := helpers.ParseSymmetry(query.Get("symmetry"))
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		fractal.Symmetry = symmetry
+	}
+	if query.Has("symmetry_center") {
+		center, err := helpers.ParsePoint(query.Get("symmetry_center"))
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		fractal.SymmetryCenter = center
+	}
 	if query.Has("background") {
 		background, err := helpers.ParseColor(query.Get("background"))
 		if err != nil {
@@ -130,6 +205,16 @@ func GetIFS(ctx iris.Context) {
 		}
 		fractal.Background = background
 	}
-	ctx.ContentType("image/png")
-	fractal.WriteImage(ctx.ResponseWriter())
+	output, err := getOutputOptions(ctx, helpers.RASTER_FORMATS)
+	if err != nil {
+		ctx.Text(err.Error())
+		return
+	}
+	fractal.BitDepth = output.BitDepth()
+	img, err := fractal.CreateImage()
+	if err != nil {
+		ctx.Text(err.Error())
+		return
+	}
+	writeImage(ctx, output, img)
 }
@@ -0,0 +1,22 @@
+package controllers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetIFSColors(t *testing.T) {
+	target := "/ifs?width=64&height=64&iterations=1000&colors=mahogany,mahogany,mahogany,mahogany"
+	recorder := serveTestRequest(t, "/ifs", GetIFS, target, nil)
+	if contentType := recorder.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "image/png") {
+		t.Fatalf("got content type %q and body %q, want a PNG image", contentType, recorder.Body.String())
+	}
+}
+
+func TestGetIFSGifColors(t *testing.T) {
+	target := "/ifs?width=64&height=64&iterations=1000&format=gif&gif_colors=16&colors=mahogany"
+	recorder := serveTestRequest(t, "/ifs", GetIFS, target, nil)
+	if contentType := recorder.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "image/gif") {
+		t.Fatalf("got content type %q and body %q, want a GIF image", contentType, recorder.Body.String())
+	}
+}
@@ -1,50 +1,88 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
 	"image/color"
 	"strconv"
 
 	"github.com/B3zaleel/fractage/src/fractals"
 	"github.com/B3zaleel/fractage/src/helpers"
+	math_helper "github.com/B3zaleel/fractage/src/helpers/math"
 	"github.com/kataras/iris/v12"
 )
 
 func GetJuliaSet(ctx iris.Context) {
+	writeJuliaSet(ctx, false)
+}
+
+func GetJuliaSetParameterSpace(ctx iris.Context) {
+	writeJuliaSet(ctx, true)
+}
+
+// Writes a Julia set or the parameter plane of its series to the response.
+func writeJuliaSet(ctx iris.Context, parameterSpace bool) {
+	fractal, err := parseJuliaSet(ctx, parameterSpace)
+	if err != nil {
+		ctx.Text(err.Error())
+		return
+	}
+	output, err := getOutputOptions(ctx, helpers.RASTER_FORMATS)
+	if err != nil {
+		ctx.Text(err.Error())
+		return
+	}
+	fractal.BitDepth = output.BitDepth()
+	img, err := fractal.CreateImage()
+	if err != nil {
+		ctx.Text(err.Error())
+		return
+	}
+	writeImage(ctx, output, img)
+}
+
+// Retrieves the properties of a Julia set or the parameter plane of its
+// series from the query of a request.
+func parseJuliaSet(ctx iris.Context, parameterSpace bool) (fractals.JuliaSet, error) {
 	query := ctx.Request().URL.Query()
 	fractal := fractals.JuliaSet{
-		Width:         DEFAULT_WIDTH,
-		Height:        DEFAULT_HEIGHT,
-		C:             fractals.JULIA_SET_DEFAULT_C,
-		MaxIterations: fractals.JULIA_SET_DEFAULT_ITERATIONS,
-		BailOut:       fractals.JULIA_SET_DEFAULT_BAIL_OUT,
-		Background:    color.RGBA{255, 255, 255, 255},
+		Width:            DEFAULT_WIDTH,
+		Height:           DEFAULT_HEIGHT,
+		C:                fractals.JULIA_SET_DEFAULT_C,
+		MaxIterations:    fractals.JULIA_SET_DEFAULT_ITERATIONS,
+		BailOut:          fractals.JULIA_SET_DEFAULT_BAIL_OUT,
+		Background:       color.RGBA{255, 255, 255, 255},
+		ParameterSpace:   parameterSpace,
+		UseCriticalPoint: true,
+		MarkerColor:      color.RGBA{255, 255, 255, 255},
+		MarkerRadius:     fractals.JULIA_SET_DEFAULT_MARKER_RADIUS,
 	}
 	colorPaletteValue := fractals.JULIA_SET_DEFAULT_COLOR_PALETTE
 	regionValue := fractals.JULIA_SET_DEFAULT_REGION
+	juliaRegionValue := fractals.JULIA_SET_DEFAULT_REGION
+	if parameterSpace {
+		regionValue = fractals.JULIA_SET_DEFAULT_PARAMETER_REGION
+	}
 	seriesName := fractals.JULIA_SET_DEFAULT_SERIES_TYPE
 	variablesTxt := fractals.JULIA_SET_DEFAULT_VARIABLES_TEXT
 	if query.Has("width") {
 		width, err := strconv.Atoi(query.Get("width"))
 		if err != nil {
-			ctx.Text(err.Error())
-			return
+			return fractal, err
 		}
 		fractal.Width = width
 	}
 	if query.Has("height") {
 		height, err := strconv.Atoi(query.Get("height"))
 		if err != nil {
-			ctx.Text(err.Error())
-			return
+			return fractal, err
 		}
 		fractal.Height = height
 	}
 	if query.Has("c") {
-		c, err := strconv.ParseComplex(query.Get("c"), 64)
+		c, err := math_helper.ParseComplex(query.Get("c"))
 		if err != nil {
-			ctx.Text(err.Error())
-			return
+			return fractal, err
 		}
 		fractal.C = c
 	}
@@ -54,12 +92,10 @@ func GetJuliaSet(ctx iris.Context) {
 	if query.Has("iterations") {
 		iterations, err := strconv.Atoi(query.Get("iterations"))
 		if err != nil {
-			ctx.Text(err.Error())
-			return
+			return fractal, err
 		}
 		if iterations < 0 || iterations > fractals.JULIA_SET_MAX_ITERATIONS {
-			ctx.Text(fmt.Sprintf("Too many iterations. Max: %d\n", fractals.JULIA_SET_MAX_ITERATIONS))
-			return
+			return fractal, fmt.Errorf("Too many iterations. Max: %d\n", fractals.JULIA_SET_MAX_ITERATIONS)
 		}
 		fractal.MaxIterations = iterations
 	}
@@ -69,8 +105,7 @@ func GetJuliaSet(ctx iris.Context) {
 	if query.Has("bail_out") {
 		bailOut, err := strconv.ParseFloat(query.Get("bail_out"), 32)
 		if err != nil {
-			ctx.Text(err.Error())
-			return
+			return fractal, err
 		}
 		fractal.BailOut = bailOut
 	}
@@ -81,39 +116,69 @@ func GetJuliaSet(ctx iris.Context) {
 		variablesTxt = query.Get("variables")
 	}
 	if !fractals.IsValidJuliaSetSeriesFunction(seriesName) {
-		ctx.Text("Invalid function type")
-		return
+		return fractal, errors.New("Invalid function type")
 	}
 	fractal.SeriesFunctionName = seriesName
+	if parameterSpace {
+		if query.Has("z0") {
+			z0, err := math_helper.ParseComplex(query.Get("z0"))
+			if err != nil {
+				return fractal, err
+			}
+			fractal.Z0 = z0
+			fractal.UseCriticalPoint = false
+		}
+		if query.Has("mark_c") {
+			markC, err := strconv.ParseBool(query.Get("mark_c"))
+			if err != nil {
+				return fractal, err
+			}
+			fractal.MarkC = markC
+		}
+		if query.Has("marker_color") {
+			markerColor, err := helpers.ParseColor(query.Get("marker_color"))
+			if err != nil {
+				return fractal, err
+			}
+			fractal.MarkerColor = markerColor
+		}
+		if query.Has("companion") {
+			companion, err := strconv.ParseBool(query.Get("companion"))
+			if err != nil {
+				return fractal, err
+			}
+			fractal.Companion = companion
+		}
+		if query.Has("julia_region") {
+			juliaRegionValue = query.Get("julia_region")
+		}
+	}
 	if query.Has("background") {
 		background, err := helpers.ParseColor(query.Get("background"))
 		if err != nil {
-			ctx.Text(err.Error())
-			return
+			return fractal, err
 		}
 		fractal.Background = background
 	}
 	variables, err := fractals.ParseJuliaSetVariables(variablesTxt)
 	if err != nil {
-		ctx.Text(err.Error())
-		return
+		return fractal, err
 	}
 	region, err := helpers.ParseRect(regionValue)
 	if err != nil {
-		ctx.Text(err.Error())
-		return
+		return fractal, err
+	}
+	juliaRegion, err := helpers.ParseRect(juliaRegionValue)
+	if err != nil {
+		return fractal, err
 	}
 	colorPalette, err := helpers.ParseColorPalette(colorPaletteValue)
 	if err != nil {
-		ctx.Text(err.Error())
-		return
+		return fractal, err
 	}
 	fractal.Variables = variables
 	fractal.Region = region
+	fractal.JuliaRegion = juliaRegion
 	fractal.ColorPalette = colorPalette
-	ctx.ContentType("image/png")
-	err = fractal.WriteImage(ctx.ResponseWriter())
-	if err != nil {
-		fmt.Printf("Error: %s\n", err.Error())
-	}
+	return fractal, nil
 }
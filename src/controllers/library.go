@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/B3zaleel/fractage/src/fractals"
+	"github.com/kataras/iris/v12"
+)
+
+const (
+	LIBRARY_MAX_UPLOAD_SIZE = 1 << 20
+	// The size of the body of an upload request, which can hold several
+	// files.
+	LIBRARY_MAX_REQUEST_SIZE = 4 << 20
+)
+
+func GetLibrary(ctx iris.Context) {
+	library, err := fractals.LoadLibrary()
+	if err != nil {
+		ctx.Text(err.Error())
+		return
+	}
+	ctx.JSON(library.Names())
+}
+
+func PostLibrary(ctx iris.Context) {
+	ctx.SetMaxRequestBodySize(LIBRARY_MAX_REQUEST_SIZE)
+	err := ctx.Request().ParseMultipartForm(LIBRARY_MAX_UPLOAD_SIZE)
+	if err != nil {
+		ctx.Text(err.Error())
+		return
+	}
+	files := ctx.Request().MultipartForm.File["file"]
+	if len(files) == 0 {
+		ctx.Text("At least one file is required")
+		return
+	}
+	var imported fractals.Library
+	for _, header := range files {
+		if header.Size > LIBRARY_MAX_UPLOAD_SIZE {
+			ctx.Text(fmt.Sprintf("%s is too large. Max: %d bytes\n", header.Filename, LIBRARY_MAX_UPLOAD_SIZE))
+			return
+		}
+		file, err := header.Open()
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		content, err := io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		entries, err := fractals.ParseLibraryFile(header.Filename, content)
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		imported.Add(entries)
+	}
+	err = fractals.AddToLibrary(imported)
+	if err != nil {
+		ctx.Text(err.Error())
+		return
+	}
+	ctx.JSON(imported.Names())
+}
@@ -1,8 +1,10 @@
 package controllers
 
 import (
+	"bytes"
 	"fmt"
 	"image/color"
+	"math"
 	"strconv"
 
 	"github.com/B3zaleel/fractage/src/fractals"
@@ -13,6 +15,8 @@ import (
 const (
 	LSYSTEM_MAX_ITERATIONS                   = 500_000
 	LSYSTEM_DEFAULT_ITERATIONS               = 6
+	LSYSTEM_MAX_SYMBOLS                      = 50_000_000
+	LSYSTEM_DEFAULT_MAX_SYMBOLS              = 5_000_000
 	LSYSTEM_DEFAULT_AXIOM                    = "X"
 	LSYSTEM_DEFAULT_RULES                    = "F=FF,X=F-[[X]+X]+F[+FX]-X"
 	LSYSTEM_DEFAULT_TURNING_ANGLE            = 22.5
@@ -23,10 +27,26 @@ const (
 	LSYSTEM_DEFAULT_TURNING_ANGLE_INCREMENT  = 5
 	LSYSTEM_DEFAULT_LINE_LENGTH_SCALE_FACTOR = 0.125
 	LSYSTEM_DEFAULT_ANGLE                    = -90.0
+	LSYSTEM_DEFAULT_MARGIN                   = 0
+	LSYSTEM_DEFAULT_FIT                      = fractals.LSYSTEM_FIT_CONTAIN
+	LSYSTEM_DEFAULT_COLORING                 = fractals.LSYSTEM_COLORING_SOLID
+	LSYSTEM_DEFAULT_COLOR_PALETTE            = "rainbow"
+	LSYSTEM_DEFAULT_COLOR_COUNT              = 8
+	LSYSTEM_DEFAULT_DOT_DIAMETER             = 4
+	LSYSTEM_DEFAULT_MODE                     = fractals.LSYSTEM_MODE_2D
+	LSYSTEM_DEFAULT_PROJECTION               = fractals.LSYSTEM_PROJECTION_ORTHOGRAPHIC
+	LSYSTEM_DEFAULT_AZIMUTH                  = 0
+	LSYSTEM_DEFAULT_ELEVATION                = 0
+	LSYSTEM_DEFAULT_CAMERA_DISTANCE          = 3
+	LSYSTEM_DEFAULT_MESH                     = fractals.LSYSTEM_MESH_LINE
 	LSYSTEM_DEFAULT_DRAW_SYMBOLS             = "AB"
 	LSYSTEM_DEFAULT_SKIP_SYMBOLS             = ""
 )
 
+// The formats of the images of Lindenmayer systems, which include the 3D
+// models of the 3d mode.
+var LSYSTEM_FORMATS = append(append([]string{}, helpers.VECTOR_FORMATS...), helpers.FORMAT_OBJ)
+
 func GetLindenmayerSystem(ctx iris.Context) {
 	query := ctx.Request().URL.Query()
 	fractal := fractals.LindenmayerSystem{
@@ -34,11 +54,22 @@ func GetLindenmayerSystem(ctx iris.Context) {
 		Height:                DEFAULT_HEIGHT,
 		Axiom:                 LSYSTEM_DEFAULT_AXIOM,
 		Iterations:            LSYSTEM_DEFAULT_ITERATIONS,
+		MaxSymbols:            LSYSTEM_DEFAULT_MAX_SYMBOLS,
 		DrawSymbols:           LSYSTEM_DEFAULT_DRAW_SYMBOLS,
 		SkipSymbols:           LSYSTEM_DEFAULT_SKIP_SYMBOLS,
 		Angle:                 LSYSTEM_DEFAULT_ANGLE,
 		UseRandomColors:       true,
+		Coloring:              LSYSTEM_DEFAULT_COLORING,
+		ColorCount:            LSYSTEM_DEFAULT_COLOR_COUNT,
+		DotDiameter:           LSYSTEM_DEFAULT_DOT_DIAMETER,
+		Mode:                  LSYSTEM_DEFAULT_MODE,
+		Projection:            LSYSTEM_DEFAULT_PROJECTION,
+		Azimuth:               LSYSTEM_DEFAULT_AZIMUTH,
+		Elevation:             LSYSTEM_DEFAULT_ELEVATION,
+		CameraDistance:        LSYSTEM_DEFAULT_CAMERA_DISTANCE,
 		Focus:                 false,
+		Margin:                LSYSTEM_DEFAULT_MARGIN,
+		Fit:                   LSYSTEM_DEFAULT_FIT,
 		TurningAngle:          LSYSTEM_DEFAULT_TURNING_ANGLE,
 		Position:              LSYSTEM_DEFAULT_POSITION,
 		LineWidth:             LSYSTEM_DEFAULT_LINE_WIDTH,
@@ -49,6 +80,8 @@ func GetLindenmayerSystem(ctx iris.Context) {
 		Background:            color.RGBA{255, 255, 255, 255},
 	}
 	rulesTxt := LSYSTEM_DEFAULT_RULES
+	colorPaletteValue := LSYSTEM_DEFAULT_COLOR_PALETTE
+	mesh := LSYSTEM_DEFAULT_MESH
 	if query.Has("width") {
 		width, err := strconv.Atoi(query.Get("width"))
 		if err != nil {
@@ -65,11 +98,53 @@ func GetLindenmayerSystem(ctx iris.Context) {
 		}
 		fractal.Height = height
 	}
+	var rules map[rune][]fractals.LindenmayerRule
+	if query.Has("preset") && query.Has("name") {
+		ctx.Text("preset and name can't be used together")
+		return
+	}
+	if query.Has("preset") {
+		preset, err := fractals.FindLSystemPreset(query.Get("preset"))
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		fractal.Axiom = preset.Axiom
+		rulesTxt = preset.RulesText()
+		fractal.TurningAngle = preset.TurningAngle
+		fractal.Angle = preset.Angle
+		fractal.DrawSymbols = preset.DrawSymbols
+		fractal.SkipSymbols = preset.SkipSymbols
+		fractal.Iterations = preset.Iterations
+		fractal.IgnoreSymbols = preset.IgnoreSymbols
+		fractal.Mode = preset.Mode
+		fractal.Focus = true
+	}
+	if query.Has("name") {
+		library, err := fractals.LoadLibrary()
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		entry, err := library.FindLSystem(query.Get("name"))
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		fractal.Axiom = entry.Axiom
+		rules = entry.RewriteRules
+		fractal.TurningAngle = entry.TurningAngle
+		fractal.DrawSymbols = entry.DrawSymbols
+		fractal.SkipSymbols = entry.SkipSymbols
+		fractal.Angle = 0
+		fractal.Focus = true
+	}
 	if query.Has("axiom") {
 		fractal.Axiom = query.Get("axiom")
 	}
 	if query.Has("rules") {
 		rulesTxt = query.Get("rules")
+		rules = nil
 	}
 	if query.Has("iterations") {
 		iterations, err := strconv.Atoi(query.Get("iterations"))
@@ -83,6 +158,29 @@ func GetLindenmayerSystem(ctx iris.Context) {
 		}
 		fractal.Iterations = iterations
 	}
+	if query.Has("max_symbols") {
+		maxSymbols, err := strconv.ParseInt(query.Get("max_symbols"), 10, 64)
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		if maxSymbols < 1 || maxSymbols > LSYSTEM_MAX_SYMBOLS {
+			ctx.Text(fmt.Sprintf("max_symbols must be between 1 and %d\n", LSYSTEM_MAX_SYMBOLS))
+			return
+		}
+		fractal.MaxSymbols = maxSymbols
+	}
+	if query.Has("seed") {
+		seed, err := strconv.ParseInt(query.Get("seed"), 10, 64)
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		fractal.Seed = seed
+	}
+	if query.Has("ignore") {
+		fractal.IgnoreSymbols = query.Get("ignore")
+	}
 	if query.Has("color") {
 		color, err := helpers.ParseColor(query.Get("color"))
 		if err != nil {
@@ -92,6 +190,50 @@ func GetLindenmayerSystem(ctx iris.Context) {
 		fractal.Color = color
 		fractal.UseRandomColors = false
 	}
+	if query.Has("coloring") {
+		coloring := query.Get("coloring")
+		if !fractals.IsValidLSystemColoring(coloring) {
+			ctx.Text("Invalid coloring")
+			return
+		}
+		fractal.Coloring = coloring
+	}
+	if query.Has("color_palette") {
+		colorPaletteValue = query.Get("color_palette")
+	}
+	if query.Has("color_count") {
+		colorCount, err := strconv.Atoi(query.Get("color_count"))
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		if colorCount < 2 {
+			ctx.Text("color_count must be at least 2")
+			return
+		}
+		fractal.ColorCount = colorCount
+	}
+	if query.Has("fill_color") {
+		fillColor, err := helpers.ParseColor(query.Get("fill_color"))
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		fractal.FillColor = fillColor
+		fractal.UseFillColor = true
+	}
+	if query.Has("dot_diameter") {
+		dotDiameter, err := strconv.ParseFloat(query.Get("dot_diameter"), 64)
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		if dotDiameter < 0 {
+			ctx.Text("dot_diameter must be at least 0")
+			return
+		}
+		fractal.DotDiameter = dotDiameter
+	}
 	if query.Has("draw_symbols") {
 		fractal.DrawSymbols = query.Get("draw_symbols")
 	}
@@ -125,6 +267,27 @@ func GetLindenmayerSystem(ctx iris.Context) {
 		}
 		fractal.Focus = focus
 	}
+	if query.Has("margin") {
+		margin, err := strconv.ParseFloat(query.Get("margin"), 64)
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		maxMargin := math.Min(float64(fractal.Width), float64(fractal.Height)) / 2
+		if margin < 0 || margin >= maxMargin {
+			ctx.Text(fmt.Sprintf("margin must be at least 0 and less than %g\n", maxMargin))
+			return
+		}
+		fractal.Margin = margin
+	}
+	if query.Has("fit") {
+		fit := query.Get("fit")
+		if !fractals.IsValidLSystemFit(fit) {
+			ctx.Text("Invalid fit")
+			return
+		}
+		fractal.Fit = fit
+	}
 	if query.Has("line_width") {
 		lineWidth, err := strconv.ParseFloat(query.Get("line_width"), 32)
 		if err != nil {
@@ -166,6 +329,61 @@ func GetLindenmayerSystem(ctx iris.Context) {
 		}
 		fractal.TurningAngleIncrement = turningAngleStep
 	}
+	if query.Has("mode") {
+		mode := query.Get("mode")
+		if !fractals.IsValidLSystemMode(mode) {
+			ctx.Text("Invalid mode")
+			return
+		}
+		fractal.Mode = mode
+	}
+	if query.Has("projection") {
+		projection := query.Get("projection")
+		if !fractals.IsValidLSystemProjection(projection) {
+			ctx.Text("Invalid projection")
+			return
+		}
+		fractal.Projection = projection
+	}
+	if query.Has("azimuth") {
+		azimuth, err := strconv.ParseFloat(query.Get("azimuth"), 64)
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		fractal.Azimuth = azimuth
+	}
+	if query.Has("elevation") {
+		elevation, err := strconv.ParseFloat(query.Get("elevation"), 64)
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		if elevation < -90 || elevation > 90 {
+			ctx.Text("elevation must be between -90 and 90")
+			return
+		}
+		fractal.Elevation = elevation
+	}
+	if query.Has("camera_distance") {
+		cameraDistance, err := strconv.ParseFloat(query.Get("camera_distance"), 64)
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		if cameraDistance <= 1 {
+			ctx.Text("camera_distance must be greater than 1")
+			return
+		}
+		fractal.CameraDistance = cameraDistance
+	}
+	if query.Has("mesh") {
+		mesh = query.Get("mesh")
+		if !fractals.IsValidLSystemMesh(mesh) {
+			ctx.Text("Invalid mesh")
+			return
+		}
+	}
 	if query.Has("background") {
 		background, err := helpers.ParseColor(query.Get("background"))
 		if err != nil {
@@ -174,7 +392,15 @@ func GetLindenmayerSystem(ctx iris.Context) {
 		}
 		fractal.Background = background
 	}
-	rules, err := fractals.ParseLindenmayerRules(rulesTxt)
+	var err error
+	if rules == nil {
+		rules, err = fractals.ParseLindenmayerRules(rulesTxt)
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+	}
+	_, err = fractals.ParseLindenmayerAxiom(fractal.Axiom)
 	if err != nil {
 		ctx.Text(err.Error())
 		return
@@ -185,9 +411,47 @@ func GetLindenmayerSystem(ctx iris.Context) {
 		return
 	}
 	fractal.RewriteRules = rules
-	ctx.ContentType("image/png")
-	err = fractal.WriteImage(ctx.ResponseWriter())
+	if fractal.Coloring != fractals.LSYSTEM_COLORING_SOLID {
+		colorPalette, err := helpers.ParseColorPalette(colorPaletteValue)
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		fractal.ColorPalette = colorPalette
+	}
+	err = fractal.CheckSymbolBudget()
+	if err != nil {
+		ctx.Text(err.Error())
+		return
+	}
+	output, err := getOutputOptions(ctx, LSYSTEM_FORMATS)
 	if err != nil {
 		ctx.Text(err.Error())
+		return
+	}
+	if output.Format == helpers.FORMAT_OBJ {
+		if fractal.Mode != fractals.LSYSTEM_MODE_3D {
+			ctx.Text("The obj format needs the 3d mode")
+			return
+		}
+		var document bytes.Buffer
+		err = fractal.WriteOBJ(&document, mesh)
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		setOutputHeaders(ctx, output)
+		document.WriteTo(ctx.ResponseWriter())
+		return
+	}
+	writeVectorImage(ctx, output, &fractal)
+}
+
+func GetLindenmayerSystemPresets(ctx iris.Context) {
+	presets, err := fractals.LoadLSystemPresets()
+	if err != nil {
+		ctx.Text(err.Error())
+		return
 	}
+	ctx.JSON(presets)
 }
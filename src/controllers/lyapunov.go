@@ -0,0 +1,134 @@
+package controllers
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+
+	"github.com/B3zaleel/fractage/src/fractals"
+	"github.com/B3zaleel/fractage/src/helpers"
+	"github.com/kataras/iris/v12"
+)
+
+func GetLyapunov(ctx iris.Context) {
+	query := ctx.Request().URL.Query()
+	fractal := fractals.Lyapunov{
+		Width:            DEFAULT_WIDTH,
+		Height:           DEFAULT_HEIGHT,
+		Sequence:         fractals.LYAPUNOV_DEFAULT_SEQUENCE,
+		WarmUpIterations: fractals.LYAPUNOV_DEFAULT_WARM_UP_ITERATIONS,
+		Iterations:       fractals.LYAPUNOV_DEFAULT_ITERATIONS,
+		X0:               fractals.LYAPUNOV_DEFAULT_X0,
+		Background:       color.RGBA{0, 0, 0, 255},
+	}
+	stableColorPaletteValue := fractals.LYAPUNOV_DEFAULT_STABLE_COLOR_PALETTE
+	chaoticColorPaletteValue := fractals.LYAPUNOV_DEFAULT_CHAOTIC_COLOR_PALETTE
+	regionValue := fractals.LYAPUNOV_DEFAULT_REGION
+	if query.Has("width") {
+		width, err := strconv.Atoi(query.Get("width"))
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		fractal.Width = width
+	}
+	if query.Has("height") {
+		height, err := strconv.Atoi(query.Get("height"))
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		fractal.Height = height
+	}
+	if query.Has("sequence") {
+		sequence := query.Get("sequence")
+		if !fractals.IsValidLyapunovSequence(sequence) {
+			ctx.Text("The sequence must consist of only A and B")
+			return
+		}
+		fractal.Sequence = sequence
+	}
+	if query.Has("region") {
+		regionValue = query.Get("region")
+	}
+	if query.Has("warm_up") {
+		warmUp, err := strconv.Atoi(query.Get("warm_up"))
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		if warmUp < 0 || warmUp > fractals.LYAPUNOV_MAX_ITERATIONS {
+			ctx.Text(fmt.Sprintf("Too many warm up iterations. Max: %d\n", fractals.LYAPUNOV_MAX_ITERATIONS))
+			return
+		}
+		fractal.WarmUpIterations = warmUp
+	}
+	if query.Has("iterations") {
+		iterations, err := strconv.Atoi(query.Get("iterations"))
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		if iterations < 1 || iterations > fractals.LYAPUNOV_MAX_ITERATIONS {
+			ctx.Text(fmt.Sprintf("iterations must be between 1 and %d\n", fractals.LYAPUNOV_MAX_ITERATIONS))
+			return
+		}
+		fractal.Iterations = iterations
+	}
+	if query.Has("x0") {
+		x0, err := strconv.ParseFloat(query.Get("x0"), 64)
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		if x0 < 0 || x0 > 1 {
+			ctx.Text("x0 must be between 0 and 1")
+			return
+		}
+		fractal.X0 = x0
+	}
+	if query.Has("stable_color_palette") {
+		stableColorPaletteValue = query.Get("stable_color_palette")
+	}
+	if query.Has("chaotic_color_palette") {
+		chaoticColorPaletteValue = query.Get("chaotic_color_palette")
+	}
+	if query.Has("background") {
+		background, err := helpers.ParseColor(query.Get("background"))
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		fractal.Background = background
+	}
+	region, err := helpers.ParseRect(regionValue)
+	if err != nil {
+		ctx.Text(err.Error())
+		return
+	}
+	stableColorPalette, err := helpers.ParseColorPalette(stableColorPaletteValue)
+	if err != nil {
+		ctx.Text(err.Error())
+		return
+	}
+	chaoticColorPalette, err := helpers.ParseColorPalette(chaoticColorPaletteValue)
+	if err != nil {
+		ctx.Text(err.Error())
+		return
+	}
+	fractal.Region = region
+	fractal.StableColorPalette = stableColorPalette
+	fractal.ChaoticColorPalette = chaoticColorPalette
+	output, err := getOutputOptions(ctx, helpers.RASTER_FORMATS)
+	if err != nil {
+		ctx.Text(err.Error())
+		return
+	}
+	fractal.BitDepth = output.BitDepth()
+	img, err := fractal.CreateImage()
+	if err != nil {
+		ctx.Text(err.Error())
+		return
+	}
+	writeImage(ctx, output, img)
+}
@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kataras/iris/v12"
+)
+
+// Runs the tests from the root of the repository, which the data files are
+// read relative to.
+func TestMain(m *testing.M) {
+	err := os.Chdir(filepath.Join("..", ".."))
+	if err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// Sends a GET request to an application that serves the given handler at
+// the given path.
+func serveTestRequest(t *testing.T, path string, handler iris.Handler, target string, header http.Header) *httptest.ResponseRecorder {
+	t.Helper()
+	app := iris.New()
+	app.Get(path, handler)
+	if err := app.Build(); err != nil {
+		t.Fatal(err)
+	}
+	request := httptest.NewRequest(http.MethodGet, target, nil)
+	for key, values := range header {
+		request.Header[key] = values
+	}
+	recorder := httptest.NewRecorder()
+	app.ServeHTTP(recorder, request)
+	return recorder
+}
@@ -20,6 +20,27 @@ const (
 )
 
 func GetMandelbrotSet(ctx iris.Context) {
+	fractal, err := parseMandelbrotSet(ctx)
+	if err != nil {
+		ctx.Text(err.Error())
+		return
+	}
+	output, err := getOutputOptions(ctx, helpers.RASTER_FORMATS)
+	if err != nil {
+		ctx.Text(err.Error())
+		return
+	}
+	fractal.BitDepth = output.BitDepth()
+	img, err := fractal.CreateImage()
+	if err != nil {
+		ctx.Text(err.Error())
+		return
+	}
+	writeImage(ctx, output, img)
+}
+
+// Retrieves the properties of a Mandelbrot set from the query of a request.
+func parseMandelbrotSet(ctx iris.Context) (fractals.MandelbrotSet, error) {
 	query := ctx.Request().URL.Query()
 	fractal := fractals.MandelbrotSet{
 		Width:         DEFAULT_WIDTH,
@@ -34,16 +55,14 @@ func GetMandelbrotSet(ctx iris.Context) {
 	if query.Has("width") {
 		width, err := strconv.Atoi(query.Get("width"))
 		if err != nil {
-			ctx.Text(err.Error())
-			return
+			return fractal, err
 		}
 		fractal.Width = width
 	}
 	if query.Has("height") {
 		height, err := strconv.Atoi(query.Get("height"))
 		if err != nil {
-			ctx.Text(err.Error())
-			return
+			return fractal, err
 		}
 		fractal.Height = height
 	}
@@ -53,20 +72,17 @@ func GetMandelbrotSet(ctx iris.Context) {
 	if query.Has("iterations") {
 		iterations, err := strconv.Atoi(query.Get("iterations"))
 		if err != nil {
-			ctx.Text(err.Error())
-			return
+			return fractal, err
 		}
 		if iterations < 0 || iterations > MANDELBROT_SET_MAX_ITERATIONS {
-			ctx.Text(fmt.Sprintf("Too many iterations. Max: %d\n", MANDELBROT_SET_MAX_ITERATIONS))
-			return
+			return fractal, fmt.Errorf("Too many iterations. Max: %d\n", MANDELBROT_SET_MAX_ITERATIONS)
 		}
 		fractal.MaxIterations = iterations
 	}
 	if query.Has("m") {
 		m, err := strconv.ParseFloat(query.Get("m"), 64)
 		if err != nil {
-			ctx.Text(err.Error())
-			return
+			return fractal, err
 		}
 		fractal.M = m
 	}
@@ -76,34 +92,26 @@ func GetMandelbrotSet(ctx iris.Context) {
 	if query.Has("bail_out") {
 		bailOut, err := strconv.ParseFloat(query.Get("bail_out"), 32)
 		if err != nil {
-			ctx.Text(err.Error())
-			return
+			return fractal, err
 		}
 		fractal.BailOut = bailOut
 	}
 	if query.Has("background") {
 		background, err := helpers.ParseColor(query.Get("background"))
 		if err != nil {
-			ctx.Text(err.Error())
-			return
+			return fractal, err
 		}
 		fractal.Background = background
 	}
 	region, err := helpers.ParseRect(regionValue)
 	if err != nil {
-		ctx.Text(err.Error())
-		return
+		return fractal, err
 	}
 	colorPalette, err := helpers.ParseColorPalette(colorPaletteValue)
 	if err != nil {
-		ctx.Text(err.Error())
-		return
+		return fractal, err
 	}
 	fractal.Region = region
 	fractal.ColorPalette = colorPalette
-	ctx.ContentType("image/png")
-	err = fractal.WriteImage(ctx.ResponseWriter())
-	if err != nil {
-		fmt.Printf("Error: %s\n", err.Error())
-	}
+	return fractal, nil
 }
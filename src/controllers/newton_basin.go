@@ -1,9 +1,11 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
 	"image/color"
 	"strconv"
+	"strings"
 
 	"github.com/B3zaleel/fractage/src/fractals"
 	"github.com/B3zaleel/fractage/src/helpers"
@@ -12,15 +14,37 @@ import (
 )
 
 const (
-	NEWTON_BASIN_MAX_ITERATIONS        = 500_000
-	NEWTON_BASIN_DEFAULT_ITERATIONS    = 32
-	NEWTON_BASIN_DEFAULT_COLOR_PALETTE = "orange_blue"
-	NEWTON_BASIN_DEFAULT_BAIL_OUT      = 1e15
-	NEWTON_BASIN_DEFAULT_POLYNOMIAL    = "-1+x^5"
-	NEWTON_BASIN_DEFAULT_REGION        = "-2, -1.5, 4, 3"
+	NEWTON_BASIN_MAX_ITERATIONS         = 500_000
+	NEWTON_BASIN_DEFAULT_ITERATIONS     = 32
+	NEWTON_BASIN_DEFAULT_COLOR_PALETTE  = "orange_blue"
+	NEWTON_BASIN_DEFAULT_BAIL_OUT       = 1e15
+	NEWTON_BASIN_DEFAULT_POLYNOMIAL     = "-1+x^5"
+	NEWTON_BASIN_DEFAULT_REGION         = "-2, -1.5, 4, 3"
+	NEWTON_BASIN_MAX_ROOT_MARKER_RADIUS = 100
 )
 
 func GetNewtonBasin(ctx iris.Context) {
+	fractal, err := parseNewtonBasin(ctx)
+	if err != nil {
+		ctx.Text(err.Error())
+		return
+	}
+	output, err := getOutputOptions(ctx, helpers.RASTER_FORMATS)
+	if err != nil {
+		ctx.Text(err.Error())
+		return
+	}
+	fractal.BitDepth = output.BitDepth()
+	img, err := fractal.CreateImage()
+	if err != nil {
+		ctx.Text(err.Error())
+		return
+	}
+	writeImage(ctx, output, img)
+}
+
+// Retrieves the properties of a Newton basin from the query of a request.
+func parseNewtonBasin(ctx iris.Context) (fractals.NewtonBasin, error) {
 	query := ctx.Request().URL.Query()
 	fractal := fractals.NewtonBasin{
 		Width:            DEFAULT_WIDTH,
@@ -29,6 +53,15 @@ func GetNewtonBasin(ctx iris.Context) {
 		BailOut:          NEWTON_BASIN_DEFAULT_BAIL_OUT,
 		UseDynamicColors: true,
 		Background:       color.RGBA{255, 255, 255, 255},
+		RootTolerance:    fractals.NEWTON_BASIN_DEFAULT_ROOT_TOLERANCE,
+		RootMarkerColor:  color.RGBA{255, 255, 255, 255},
+		RootMarkerRadius: fractals.NEWTON_BASIN_DEFAULT_ROOT_MARKER_RADIUS,
+		Method:           fractals.NEWTON_BASIN_DEFAULT_METHOD,
+		Order:            fractals.NEWTON_BASIN_DEFAULT_ORDER,
+		Relaxation:       fractals.NEWTON_BASIN_DEFAULT_RELAXATION,
+		NovaMode:         fractals.NEWTON_BASIN_NOVA_MODE_PARAMETER,
+		Z0:               fractals.NEWTON_BASIN_DEFAULT_Z0,
+		DerivativeMode:   fractals.NEWTON_BASIN_DERIVATIVE_SYMBOLIC,
 	}
 	colorPaletteValue := NEWTON_BASIN_DEFAULT_COLOR_PALETTE
 	regionValue := NEWTON_BASIN_DEFAULT_REGION
@@ -36,16 +69,14 @@ func GetNewtonBasin(ctx iris.Context) {
 	if query.Has("width") {
 		width, err := strconv.Atoi(query.Get("width"))
 		if err != nil {
-			ctx.Text(err.Error())
-			return
+			return fractal, err
 		}
 		fractal.Width = width
 	}
 	if query.Has("height") {
 		height, err := strconv.Atoi(query.Get("height"))
 		if err != nil {
-			ctx.Text(err.Error())
-			return
+			return fractal, err
 		}
 		fractal.Height = height
 	}
@@ -59,12 +90,10 @@ func GetNewtonBasin(ctx iris.Context) {
 	if query.Has("iterations") {
 		iterations, err := strconv.Atoi(query.Get("iterations"))
 		if err != nil {
-			ctx.Text(err.Error())
-			return
+			return fractal, err
 		}
 		if iterations < 0 || iterations > NEWTON_BASIN_MAX_ITERATIONS {
-			ctx.Text(fmt.Sprintf("Too many iterations. Max: %d\n", NEWTON_BASIN_MAX_ITERATIONS))
-			return
+			return fractal, fmt.Errorf("Too many iterations. Max: %d\n", NEWTON_BASIN_MAX_ITERATIONS)
 		}
 		fractal.MaxIterations = iterations
 	}
@@ -74,40 +103,160 @@ func GetNewtonBasin(ctx iris.Context) {
 	if query.Has("bail_out") {
 		bailOut, err := strconv.ParseFloat(query.Get("bail_out"), 32)
 		if err != nil {
-			ctx.Text(err.Error())
-			return
+			return fractal, err
 		}
 		fractal.BailOut = bailOut
 	}
+	if query.Has("root_colors") {
+		useRootColors, err := strconv.ParseBool(query.Get("root_colors"))
+		if err != nil {
+			return fractal, err
+		}
+		fractal.UseRootColors = useRootColors
+	}
+	if query.Has("root_tolerance") {
+		rootTolerance, err := strconv.ParseFloat(query.Get("root_tolerance"), 64)
+		if err != nil {
+			return fractal, err
+		}
+		if rootTolerance <= 0 {
+			return fractal, errors.New("root_tolerance must be greater than 0")
+		}
+		fractal.RootTolerance = rootTolerance
+	}
+	if query.Has("mark_roots") {
+		markRoots, err := strconv.ParseBool(query.Get("mark_roots"))
+		if err != nil {
+			return fractal, err
+		}
+		fractal.MarkRoots = markRoots
+	}
+	if query.Has("root_marker_color") {
+		rootMarkerColor, err := helpers.ParseColor(query.Get("root_marker_color"))
+		if err != nil {
+			return fractal, err
+		}
+		fractal.RootMarkerColor = rootMarkerColor
+	}
+	if query.Has("root_marker_radius") {
+		rootMarkerRadius, err := strconv.ParseFloat(query.Get("root_marker_radius"), 64)
+		if err != nil {
+			return fractal, err
+		}
+		if rootMarkerRadius < 0 || rootMarkerRadius > NEWTON_BASIN_MAX_ROOT_MARKER_RADIUS {
+			return fractal, fmt.Errorf("root_marker_radius must be between 0 and %d\n", NEWTON_BASIN_MAX_ROOT_MARKER_RADIUS)
+		}
+		fractal.RootMarkerRadius = rootMarkerRadius
+	}
+	if query.Has("method") {
+		method := query.Get("method")
+		if !fractals.IsValidNewtonBasinMethod(method) {
+			return fractal, errors.New("Invalid method")
+		}
+		fractal.Method = strings.Trim(method, helpers.WHITESPACE_CUTSET)
+	}
+	if query.Has("order") {
+		order, err := strconv.Atoi(query.Get("order"))
+		if err != nil {
+			return fractal, err
+		}
+		if order < 1 || order > fractals.NEWTON_BASIN_MAX_ORDER {
+			return fractal, fmt.Errorf("order must be between 1 and %d\n", fractals.NEWTON_BASIN_MAX_ORDER)
+		}
+		fractal.Order = order
+	}
+	if query.Has("a") {
+		relaxation, err := math_helper.ParseComplex(query.Get("a"))
+		if err != nil {
+			return fractal, err
+		}
+		fractal.Relaxation = relaxation
+	}
+	if query.Has("nova_mode") {
+		novaMode := strings.Trim(query.Get("nova_mode"), helpers.WHITESPACE_CUTSET)
+		if novaMode != fractals.NEWTON_BASIN_NOVA_MODE_JULIA && novaMode != fractals.NEWTON_BASIN_NOVA_MODE_PARAMETER {
+			return fractal, errors.New("Invalid nova mode")
+		}
+		fractal.NovaMode = novaMode
+	}
+	if query.Has("c") {
+		c, err := math_helper.ParseComplex(query.Get("c"))
+		if err != nil {
+			return fractal, err
+		}
+		fractal.C = c
+	}
+	if query.Has("z0") {
+		z0, err := math_helper.ParseComplex(query.Get("z0"))
+		if err != nil {
+			return fractal, err
+		}
+		fractal.Z0 = z0
+	}
+	if query.Has("derivative") {
+		derivativeMode := query.Get("derivative")
+		if derivativeMode != fractals.NEWTON_BASIN_DERIVATIVE_SYMBOLIC &&
+			derivativeMode != fractals.NEWTON_BASIN_DERIVATIVE_NUMERIC {
+			return fractal, errors.New("Invalid derivative mode")
+		}
+		fractal.DerivativeMode = derivativeMode
+	}
+	if fractal.Method == "nova" && fractal.UseRootColors {
+		return fractal, errors.New("root_colors can't be used with the nova method")
+	}
 	if query.Has("background") {
 		background, err := helpers.ParseColor(query.Get("background"))
 		if err != nil {
-			ctx.Text(err.Error())
-			return
+			return fractal, err
 		}
 		fractal.Background = background
 	}
 	region, err := helpers.ParseRect(regionValue)
 	if err != nil {
-		ctx.Text(err.Error())
-		return
+		return fractal, err
 	}
-	polynomial, err := math_helper.ParseCmplxPolynomial(polynomialValue)
-	if err != nil {
-		ctx.Text(err.Error())
-		return
+	inputCount := 0
+	for _, key := range []string{"polynomial", "roots", "function"} {
+		if query.Has(key) {
+			inputCount++
+		}
+	}
+	if inputCount > 1 {
+		return fractal, errors.New("Only one of polynomial, roots, and function can be specified")
+	}
+	if query.Has("function") {
+		expression, err := math_helper.ParseCmplxExpression(query.Get("function"))
+		if err != nil {
+			return fractal, err
+		}
+		fractal.Expression = &expression
+	} else if query.Has("roots") {
+		values, err := helpers.GetCSV(query.Get("roots"))
+		if err != nil {
+			return fractal, err
+		}
+		roots, err := math_helper.ParseComplexList(values)
+		if err != nil {
+			return fractal, err
+		}
+		polynomial, err := math_helper.CmplxPolynomialFromRoots(roots, 'x')
+		if err != nil {
+			return fractal, err
+		}
+		fractal.Polynomial = polynomial
+		fractal.Roots = roots
+	} else {
+		polynomial, err := math_helper.ParseCmplxPolynomial(polynomialValue)
+		if err != nil {
+			return fractal, err
+		}
+		fractal.Polynomial = polynomial
 	}
-	fractal.Polynomial = polynomial
 	colorPalette, err := helpers.ParseColorPalette(colorPaletteValue)
 	if err != nil {
-		ctx.Text(err.Error())
-		return
+		return fractal, err
 	}
 	fractal.Region = region
 	fractal.ColorPalette = colorPalette
-	ctx.ContentType("image/png")
-	err = fractal.WriteImage(ctx.ResponseWriter())
-	if err != nil {
-		fmt.Printf("Error: %s\n", err.Error())
-	}
+	return fractal, nil
 }
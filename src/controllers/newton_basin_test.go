@@ -0,0 +1,36 @@
+package controllers
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestGetNewtonBasinNovaMode(t *testing.T) {
+	target := "/newton-basin?width=32&height=32&method=%20nova&nova_mode=%20julia"
+	recorder := serveTestRequest(t, "/newton-basin", GetNewtonBasin, target, nil)
+	if contentType := recorder.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "image/png") {
+		t.Fatalf("got content type %q and body %q, want a PNG image", contentType, recorder.Body.String())
+	}
+}
+
+func TestGetNewtonBasinNovaRootMarkers(t *testing.T) {
+	tests := []struct {
+		c      string
+		marked bool
+	}{
+		{"0", true},
+		{"0.2", false},
+	}
+	for _, test := range tests {
+		target := "/newton-basin?width=32&height=32&method=nova&nova_mode=julia&c=" + test.c
+		unmarked := serveTestRequest(t, "/newton-basin", GetNewtonBasin, target, nil)
+		marked := serveTestRequest(t, "/newton-basin", GetNewtonBasin, target+"&mark_roots=true", nil)
+		if !strings.HasPrefix(marked.Header().Get("Content-Type"), "image/png") {
+			t.Fatalf("c=%s: got body %q, want a PNG image", test.c, marked.Body.String())
+		}
+		if !bytes.Equal(unmarked.Body.Bytes(), marked.Body.Bytes()) != test.marked {
+			t.Errorf("c=%s: got root markers %t, want %t", test.c, !test.marked, test.marked)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"mime"
+	"strconv"
+	"strings"
+
+	"github.com/B3zaleel/fractage/src/helpers"
+	"github.com/kataras/iris/v12"
+)
+
+// Represents a fractal that's drawn with paths, which can be written as a
+// raster image or as a vector document.
+type vectorFractal interface {
+	CreateImage() (image.Image, error)
+	WriteSVG(output io.Writer) error
+	WritePDF(output io.Writer, options helpers.PdfOptions) error
+}
+
+// Retrieves how the image of a request is encoded. The format is picked from
+// the format parameter, or else from the formats the Accept header prefers,
+// and defaults to the first of the given formats.
+func getOutputOptions(ctx iris.Context, formats []string) (helpers.OutputOptions, error) {
+	query := ctx.Request().URL.Query()
+	options := helpers.OutputOptions{
+		Format:  formats[0],
+		Quality: helpers.OUTPUT_DEFAULT_QUALITY,
+		Colors:  helpers.OUTPUT_DEFAULT_COLORS,
+	}
+	if query.Has("format") {
+		options.Format = strings.ToLower(query.Get("format"))
+		if !containsFormat(formats, options.Format) {
+			return options, errors.New("Invalid format")
+		}
+	} else {
+		options.Negotiated = true
+		if accept := ctx.GetHeader("Accept"); accept != "" {
+			options.Format = negotiateFormat(accept, formats)
+		}
+	}
+	if query.Has("quality") {
+		quality, err := strconv.Atoi(query.Get("quality"))
+		if err != nil {
+			return options, err
+		}
+		if quality < 1 || quality > 100 {
+			return options, errors.New("quality must be between 1 and 100")
+		}
+		options.Quality = quality
+	}
+	if query.Has("gif_colors") {
+		colors, err := strconv.Atoi(query.Get("gif_colors"))
+		if err != nil {
+			return options, err
+		}
+		if colors < 2 || colors > 256 {
+			return options, errors.New("gif_colors must be between 2 and 256")
+		}
+		options.Colors = colors
+	}
+	if query.Has("dither") {
+		dither, err := strconv.ParseBool(query.Get("dither"))
+		if err != nil {
+			return options, err
+		}
+		options.Dither = dither
+	}
+	pdfOptions, err := getPdfOptions(ctx)
+	if err != nil {
+		return options, err
+	}
+	options.Pdf = pdfOptions
+	return options, nil
+}
+
+func containsFormat(formats []string, format string) bool {
+	for _, f := range formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// Picks the format whose media type has the highest quality in an Accept
+// header, using the quality of the most specific media range that matches
+// it. Ties go to the format that comes first.
+func negotiateFormat(accept string, formats []string) string {
+	best, bestQuality := formats[0], 0.0
+	for _, format := range formats {
+		quality := acceptQuality(accept, helpers.FORMAT_MEDIA_TYPES[format])
+		if quality > bestQuality {
+			best, bestQuality = format, quality
+		}
+	}
+	return best
+}
+
+// Retrieves the quality an Accept header gives a media type, which is 0 when
+// no media range matches it.
+func acceptQuality(accept, mediaType string) float64 {
+	quality, specificity := 0.0, -1
+	for _, mediaRange := range strings.Split(accept, ",") {
+		rangeType, params, err := mime.ParseMediaType(mediaRange)
+		if err != nil {
+			continue
+		}
+		s := -1
+		if rangeType == mediaType {
+			s = 2
+		} else if rangeType == "*/*" {
+			s = 0
+		} else if strings.HasSuffix(rangeType, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(rangeType, "*")) {
+			s = 1
+		}
+		if s <= specificity {
+			continue
+		}
+		q := 1.0
+		if params["q"] != "" {
+			q, err = strconv.ParseFloat(params["q"], 64)
+			if err != nil {
+				continue
+			}
+		}
+		quality, specificity = q, s
+	}
+	return quality
+}
+
+// Retrieves the resolution of a PDF document from the dpi parameter of a
+// request, and its metadata from the path and query of the request.
+func getPdfOptions(ctx iris.Context) (helpers.PdfOptions, error) {
+	query := ctx.Request().URL.Query()
+	options := helpers.PdfOptions{
+		DPI:        helpers.PDF_DEFAULT_DPI,
+		Title:      strings.TrimPrefix(ctx.Path(), "/"),
+		Parameters: query.Encode(),
+	}
+	if query.Has("dpi") {
+		dpi, err := strconv.ParseFloat(query.Get("dpi"), 64)
+		if err != nil {
+			return options, err
+		}
+		if dpi <= 0 {
+			return options, errors.New("dpi must be greater than 0")
+		}
+		options.DPI = dpi
+	}
+	return options, nil
+}
+
+// Sets the content type of the response to the media type of the format of
+// the options and names its file after the path of the request. Caches are
+// told that the response varies with the Accept header when the format was
+// negotiated.
+func setOutputHeaders(ctx iris.Context, options helpers.OutputOptions) {
+	name := strings.ReplaceAll(strings.Trim(ctx.Path(), "/"), "/", "-")
+	ctx.ContentType(helpers.FORMAT_MEDIA_TYPES[options.Format])
+	ctx.Header("Content-Disposition", fmt.Sprintf("inline; filename=\"%s%s\"", name, helpers.FORMAT_EXTENSIONS[options.Format]))
+	if options.Negotiated {
+		ctx.Header("Vary", "Accept")
+	}
+}
+
+// Writes an image in the format of the options.
+func writeImage(ctx iris.Context, options helpers.OutputOptions, img image.Image) {
+	setOutputHeaders(ctx, options)
+	err := helpers.EncodeImage(ctx.ResponseWriter(), img, options)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+	}
+}
+
+// Writes a vector fractal as an SVG or PDF document, or else as a raster
+// image in the format of the options. Documents are written to a buffer
+// first so that the headers are only set when they're rendered.
+func writeVectorImage(ctx iris.Context, options helpers.OutputOptions, fractal vectorFractal) {
+	var document bytes.Buffer
+	var err error
+	switch options.Format {
+	case helpers.FORMAT_SVG:
+		err = fractal.WriteSVG(&document)
+	case helpers.FORMAT_PDF:
+		err = fractal.WritePDF(&document, options.Pdf)
+	default:
+		var img image.Image
+		img, err = fractal.CreateImage()
+		if err == nil {
+			writeImage(ctx, options, img)
+			return
+		}
+	}
+	if err != nil {
+		ctx.Text(err.Error())
+		return
+	}
+	setOutputHeaders(ctx, options)
+	_, err = document.WriteTo(ctx.ResponseWriter())
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+	}
+}
@@ -64,6 +64,10 @@ func GetSierpinskiTriangle(ctx iris.Context) {
 		}
 		fractal.Background = background
 	}
-	ctx.ContentType("image/png")
-	fractal.WriteImage(ctx.ResponseWriter())
+	output, err := getOutputOptions(ctx, helpers.VECTOR_FORMATS)
+	if err != nil {
+		ctx.Text(err.Error())
+		return
+	}
+	writeVectorImage(ctx, output, &fractal)
 }
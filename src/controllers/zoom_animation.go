@@ -0,0 +1,185 @@
+package controllers
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+
+	"github.com/B3zaleel/fractage/src/fractals"
+	"github.com/B3zaleel/fractage/src/helpers"
+	"github.com/kataras/iris/v12"
+)
+
+const (
+	ZOOM_ANIMATION_FRACTAL_MANDELBROT_SET = "mandelbrot-set"
+	ZOOM_ANIMATION_FRACTAL_JULIA_SET      = "julia-set"
+	ZOOM_ANIMATION_FRACTAL_NEWTON_BASIN   = "newton-basin"
+	ZOOM_ANIMATION_DEFAULT_FRACTAL        = ZOOM_ANIMATION_FRACTAL_MANDELBROT_SET
+	// The number of pixels of all the frames of an animation.
+	ZOOM_ANIMATION_MAX_PIXELS = 100_000_000
+	// The number of frames rendered at the same time, which is kept low so
+	// that one animation doesn't occupy every CPU of the server.
+	ZOOM_ANIMATION_DEFAULT_WORKERS = 2
+)
+
+func GetZoomAnimation(ctx iris.Context) {
+	query := ctx.Request().URL.Query()
+	animation := fractals.ZoomAnimation{
+		Zoom:            fractals.ZOOM_ANIMATION_DEFAULT_ZOOM,
+		Frames:          fractals.ZOOM_ANIMATION_DEFAULT_FRAMES,
+		IterationGrowth: fractals.ZOOM_ANIMATION_DEFAULT_ITERATION_GROWTH,
+		Workers:         ZOOM_ANIMATION_DEFAULT_WORKERS,
+	}
+	if animation.Workers > runtime.NumCPU() {
+		animation.Workers = runtime.NumCPU()
+	}
+	delay := helpers.ANIMATION_DEFAULT_DELAY
+	loops := 0
+	fractalName := ZOOM_ANIMATION_DEFAULT_FRACTAL
+	if query.Has("fractal") {
+		fractalName = query.Get("fractal")
+	}
+	var width, height int
+	switch fractalName {
+	case ZOOM_ANIMATION_FRACTAL_MANDELBROT_SET:
+		fractal, err := parseMandelbrotSet(ctx)
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		animation.Fractal = &fractal
+		animation.Region, animation.Iterations = fractal.Region, fractal.MaxIterations
+		animation.MaxIterations = MANDELBROT_SET_MAX_ITERATIONS
+		width, height = fractal.Width, fractal.Height
+	case ZOOM_ANIMATION_FRACTAL_JULIA_SET:
+		fractal, err := parseJuliaSet(ctx, false)
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		animation.Fractal = &fractal
+		animation.Region, animation.Iterations = fractal.Region, fractal.MaxIterations
+		animation.MaxIterations = fractals.JULIA_SET_MAX_ITERATIONS
+		width, height = fractal.Width, fractal.Height
+	case ZOOM_ANIMATION_FRACTAL_NEWTON_BASIN:
+		fractal, err := parseNewtonBasin(ctx)
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		animation.Fractal = &fractal
+		animation.Region, animation.Iterations = fractal.Region, fractal.MaxIterations
+		animation.MaxIterations = NEWTON_BASIN_MAX_ITERATIONS
+		width, height = fractal.Width, fractal.Height
+	default:
+		ctx.Text("Invalid fractal")
+		return
+	}
+	animation.Center = helpers.Point{
+		X: animation.Region.X + animation.Region.Width/2,
+		Y: animation.Region.Y + animation.Region.Height/2,
+	}
+	if query.Has("center") {
+		center, err := helpers.ParsePoint(query.Get("center"))
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		animation.Center = center
+	}
+	if query.Has("zoom") {
+		zoom, err := strconv.ParseFloat(query.Get("zoom"), 64)
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		if zoom <= 0 {
+			ctx.Text("zoom must be greater than 0")
+			return
+		}
+		animation.Zoom = zoom
+	}
+	if query.Has("frames") {
+		frames, err := strconv.Atoi(query.Get("frames"))
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		if frames < 1 || frames > fractals.ZOOM_ANIMATION_MAX_FRAMES {
+			ctx.Text(fmt.Sprintf("frames must be between 1 and %d\n", fractals.ZOOM_ANIMATION_MAX_FRAMES))
+			return
+		}
+		animation.Frames = frames
+	}
+	if query.Has("iteration_growth") {
+		iterationGrowth, err := strconv.ParseFloat(query.Get("iteration_growth"), 64)
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		if iterationGrowth < 0 {
+			ctx.Text("iteration_growth must not be negative")
+			return
+		}
+		animation.IterationGrowth = iterationGrowth
+	}
+	if query.Has("workers") {
+		workers, err := strconv.Atoi(query.Get("workers"))
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		if workers < 1 || workers > runtime.NumCPU() {
+			ctx.Text(fmt.Sprintf("workers must be between 1 and %d\n", runtime.NumCPU()))
+			return
+		}
+		animation.Workers = workers
+	}
+	if query.Has("delay") {
+		var err error
+		delay, err = strconv.Atoi(query.Get("delay"))
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		if delay < 1 || delay > helpers.ANIMATION_MAX_DELAY {
+			ctx.Text(fmt.Sprintf("delay must be between 1 and %d\n", helpers.ANIMATION_MAX_DELAY))
+			return
+		}
+	}
+	if query.Has("loops") {
+		var err error
+		loops, err = strconv.Atoi(query.Get("loops"))
+		if err != nil {
+			ctx.Text(err.Error())
+			return
+		}
+		if loops < 0 {
+			ctx.Text("loops must not be negative")
+			return
+		}
+	}
+	if animation.Depth() > fractals.ZOOM_ANIMATION_MAX_DEPTH {
+		ctx.Text(fmt.Sprintf("The zoom is too deep. Max depth: %g\n", fractals.ZOOM_ANIMATION_MAX_DEPTH))
+		return
+	}
+	if animation.Frames*width*height > ZOOM_ANIMATION_MAX_PIXELS {
+		ctx.Text(fmt.Sprintf("Too many pixels in all the frames. Max: %d\n", ZOOM_ANIMATION_MAX_PIXELS))
+		return
+	}
+	output, err := getOutputOptions(ctx, helpers.ANIMATION_FORMATS)
+	if err != nil {
+		ctx.Text(err.Error())
+		return
+	}
+	frames, err := animation.CreateFrames()
+	if err != nil {
+		ctx.Text(err.Error())
+		return
+	}
+	setOutputHeaders(ctx, output)
+	err = helpers.EncodeAnimation(ctx.ResponseWriter(), helpers.Animation{Frames: frames, Delay: delay, Loops: loops}, output)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+	}
+}
@@ -0,0 +1,224 @@
+package fractals
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strings"
+
+	"github.com/B3zaleel/fractage/src/helpers"
+)
+
+const (
+	ATTRACTOR_MAX_ITERATIONS        = 100_000_000
+	ATTRACTOR_DEFAULT_ITERATIONS    = 2_000_000
+	ATTRACTOR_WARM_UP_ITERATIONS    = 100
+	ATTRACTOR_DEFAULT_TYPE          = "clifford"
+	ATTRACTOR_DEFAULT_COLOR_PALETTE = "fire"
+	ATTRACTOR_DEFAULT_TONE_MAPPING  = helpers.TONE_MAPPING_LOG
+	ATTRACTOR_DEFAULT_GAMMA         = 2.2
+	ATTRACTOR_DEFAULT_MARGIN        = 10
+)
+
+var (
+	ATTRACTOR_TYPES = map[string]AttractorType{
+		"clifford": {
+			Parameters: []string{"a", "b", "c", "d"},
+			Defaults:   []float64{-1.4, 1.6, 1.0, 0.7},
+			Start:      helpers.Point{X: 0.1, Y: 0.1},
+			Fxn:        clifford_attractor,
+		},
+		"de_jong": {
+			Parameters: []string{"a", "b", "c", "d"},
+			Defaults:   []float64{1.641, 1.902, 0.316, 1.525},
+			Start:      helpers.Point{X: 0.1, Y: 0.1},
+			Fxn:        de_jong_attractor,
+		},
+		"henon": {
+			Parameters: []string{"a", "b"},
+			Defaults:   []float64{1.4, 0.3},
+			Start:      helpers.Point{X: 0.1, Y: 0.1},
+			Fxn:        henon_attractor,
+		},
+		"ikeda": {
+			Parameters: []string{"u"},
+			Defaults:   []float64{0.9},
+			Start:      helpers.Point{X: 0, Y: 0},
+			Fxn:        ikeda_attractor,
+		},
+		"gumowski_mira": {
+			Parameters: []string{"a", "sigma", "mu"},
+			Defaults:   []float64{0.008, 0.05, -0.8},
+			Start:      helpers.Point{X: 0, Y: 0.5},
+			Fxn:        gumowski_mira_attractor,
+		},
+		"tinkerbell": {
+			Parameters: []string{"a", "b", "c", "d"},
+			Defaults:   []float64{0.9, -0.6013, 2.0, 0.5},
+			Start:      helpers.Point{X: -0.72, Y: -0.64},
+			Fxn:        tinkerbell_attractor,
+		},
+		"svensson": {
+			Parameters: []string{"a", "b", "c", "d"},
+			Defaults:   []float64{1.5, -1.8, 1.6, 0.9},
+			Start:      helpers.Point{X: 0.1, Y: 0.1},
+			Fxn:        svensson_attractor,
+		},
+	}
+)
+
+// Represents a map of a strange attractor.
+type AttractorType struct {
+	// The names of the parameters of the map.
+	Parameters []string
+	// The default values of the parameters of the map.
+	Defaults []float64
+	// The default starting point of the map.
+	Start helpers.Point
+	// Computes the next point of the map, where params holds the values of
+	// the parameters in the order they are declared.
+	Fxn func(params []float64, xIn, yIn float64) (xOut, yOut float64)
+}
+
+// Properties of a strange attractor image.
+type Attractor struct {
+	Width        int
+	Height       int
+	Type         string
+	Parameters   []float64
+	X            float64
+	Y            float64
+	Iterations   int
+	ColorPalette helpers.ColorPalette
+	ToneMapping  string
+	Gamma        float64
+	Margin       float64
+	// The rotations and reflections that copy each point of the orbit
+	// around the symmetry center.
+	Symmetry       helpers.Symmetry
+	SymmetryCenter helpers.Point
+	Background     color.RGBA
+	// The number of bits per channel of the image, which is 8 or 16.
+	BitDepth int
+}
+
+// Creates the strange attractor image.
+func (props *Attractor) CreateImage() (image.Image, error) {
+	viewport := image.Rect(0, 0, props.Width, props.Height)
+	img := helpers.NewImage(viewport, props.BitDepth)
+	helpers.FillImage(img, props.Background)
+	err := props.render(img)
+	if err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+// Helper function for rendering the strange attractor.
+func (props *Attractor) render(img draw.RGBA64Image) error {
+	attractorType, ok := ATTRACTOR_TYPES[props.Type]
+	if !ok {
+		return fmt.Errorf("Unknown attractor type: %s", props.Type)
+	}
+	attractorFxn := attractorType.Fxn
+	xMin, yMin := math.Inf(1), math.Inf(1)
+	xMax, yMax := math.Inf(-1), math.Inf(-1)
+	var scale, xOffset, yOffset float64
+	density := helpers.NewDensityBuffer(props.Width, props.Height)
+	for round := 1; round < 3; round++ {
+		x, y := props.X, props.Y
+		if round == 2 {
+			bounds := helpers.Rect{X: xMin, Y: yMin, Width: xMax - xMin, Height: yMax - yMin}
+			scale, xOffset, yOffset = helpers.FitRect(bounds, float64(props.Width), float64(props.Height), props.Margin)
+		}
+		for i := 0; i < props.Iterations+ATTRACTOR_WARM_UP_ITERATIONS; i++ {
+			x, y = attractorFxn(props.Parameters, x, y)
+			if i < ATTRACTOR_WARM_UP_ITERATIONS || math.IsNaN(x) || math.IsNaN(y) || math.IsInf(x, 0) || math.IsInf(y, 0) {
+				continue
+			}
+			props.Symmetry.Images(x, y, props.SymmetryCenter, func(x, y float64) {
+				if round == 1 {
+					xMin = math.Min(xMin, x)
+					yMin = math.Min(yMin, y)
+					xMax = math.Max(xMax, x)
+					yMax = math.Max(yMax, y)
+				} else {
+					ptX := int(xOffset + x*scale)
+					ptY := int(float64(props.Height) - (yOffset + y*scale))
+					density.Hit(ptX, ptY)
+				}
+			})
+		}
+		if math.IsInf(xMin, 1) {
+			// the orbit diverged
+			return nil
+		}
+	}
+	return density.ToneMap(img, props.ColorPalette, props.ToneMapping, props.Gamma)
+}
+
+// Checks if a type name exists in the set of ATTRACTOR_TYPES names.
+func IsValidAttractorType(txt string) bool {
+	typeName := strings.Trim(txt, helpers.WHITESPACE_CUTSET)
+	for name := range ATTRACTOR_TYPES {
+		if name == typeName {
+			return true
+		}
+	}
+	return false
+}
+
+func clifford_attractor(params []float64, xIn, yIn float64) (xOut, yOut float64) {
+	a, b, c, d := params[0], params[1], params[2], params[3]
+	xOut = math.Sin(a*yIn) + c*math.Cos(a*xIn)
+	yOut = math.Sin(b*xIn) + d*math.Cos(b*yIn)
+	return
+}
+
+func de_jong_attractor(params []float64, xIn, yIn float64) (xOut, yOut float64) {
+	a, b, c, d := params[0], params[1], params[2], params[3]
+	xOut = math.Sin(a*yIn) - math.Cos(b*xIn)
+	yOut = math.Sin(c*xIn) - math.Cos(d*yIn)
+	return
+}
+
+func henon_attractor(params []float64, xIn, yIn float64) (xOut, yOut float64) {
+	a, b := params[0], params[1]
+	xOut = 1 - a*xIn*xIn + yIn
+	yOut = b * xIn
+	return
+}
+
+func ikeda_attractor(params []float64, xIn, yIn float64) (xOut, yOut float64) {
+	u := params[0]
+	t := 0.4 - 6/(1+xIn*xIn+yIn*yIn)
+	xOut = 1 + u*(xIn*math.Cos(t)-yIn*math.Sin(t))
+	yOut = u * (xIn*math.Sin(t) + yIn*math.Cos(t))
+	return
+}
+
+func gumowski_mira_attractor(params []float64, xIn, yIn float64) (xOut, yOut float64) {
+	a, sigma, mu := params[0], params[1], params[2]
+	g := func(x float64) float64 {
+		return mu*x + 2*(1-mu)*x*x/(1+x*x)
+	}
+	xOut = yIn + a*yIn*(1-sigma*yIn*yIn) + g(xIn)
+	yOut = -xIn + g(xOut)
+	return
+}
+
+func tinkerbell_attractor(params []float64, xIn, yIn float64) (xOut, yOut float64) {
+	a, b, c, d := params[0], params[1], params[2], params[3]
+	xOut = xIn*xIn - yIn*yIn + a*xIn + b*yIn
+	yOut = 2*xIn*yIn + c*xIn + d*yIn
+	return
+}
+
+func svensson_attractor(params []float64, xIn, yIn float64) (xOut, yOut float64) {
+	a, b, c, d := params[0], params[1], params[2], params[3]
+	xOut = d*math.Sin(a*xIn) - math.Sin(b*yIn)
+	yOut = c*math.Cos(a*xIn) + math.Cos(b*yIn)
+	return
+}
@@ -3,11 +3,11 @@ package fractals
 import (
 	"image"
 	"image/color"
-	"image/png"
 	"io"
 	"math"
 
 	"github.com/B3zaleel/fractage/src/helpers"
+	"github.com/llgcode/draw2d"
 	"github.com/llgcode/draw2d/draw2dimg"
 )
 
@@ -21,24 +21,41 @@ type CantorDust struct {
 	Background      color.RGBA
 }
 
-// Writes the Cantor dust image to the given output.
-func (props *CantorDust) WriteImage(output io.Writer) {
+// Creates the Cantor dust image.
+func (props *CantorDust) CreateImage() (image.Image, error) {
 	viewport := image.Rect(0, 0, props.Width, props.Height)
 	img := image.NewRGBA(viewport)
 	gc := draw2dimg.NewGraphicContext(img)
-	length := math.Min(float64(props.Width), float64(props.Height))
-	x := float64(viewport.Min.X) + float64(props.Width)/2 - length/2
-	y := float64(viewport.Min.Y) + float64(props.Height)/2 - length/2
 	helpers.FillImage(img, props.Background)
+	props.draw(gc)
+	return img, nil
+}
+
+// Writes the Cantor dust as a PDF document to the given output, whose
+// page has the size of the image at the resolution of the options.
+func (props *CantorDust) WritePDF(output io.Writer, options helpers.PdfOptions) error {
+	pdf, gc := helpers.NewPdfGraphicContext(props.Width, props.Height, props.Background, options)
+	props.draw(gc)
+	return helpers.WritePdf(output, pdf)
+}
+
+// Writes the Cantor dust as an SVG document to the given output.
+func (props *CantorDust) WriteSVG(output io.Writer) error {
+	svg, gc := helpers.NewSvgGraphicContext(props.Width, props.Height, props.Background)
+	props.draw(gc)
+	return helpers.WriteSvg(output, svg)
+}
+
+// Draws the Cantor dust in a square in the middle of the image.
+func (props *CantorDust) draw(gc draw2d.GraphicContext) {
+	length := math.Min(float64(props.Width), float64(props.Height))
+	x := float64(props.Width)/2 - length/2
+	y := float64(props.Height)/2 - length/2
 	props.render(gc, x, y, length, length, props.Iterations)
-	err := png.Encode(output, img)
-	if err != nil {
-		panic(err)
-	}
 }
 
 // Helper function for rendering the Cantor dust.
-func (props *CantorDust) render(gc *draw2dimg.GraphicContext, x, y, width, height float64, level int) {
+func (props *CantorDust) render(gc draw2d.GraphicContext, x, y, width, height float64, level int) {
 	if level > 0 {
 		dx, dy := width/3, height/3
 		props.render(gc, x, y, dx, dy, level-1)
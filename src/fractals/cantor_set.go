@@ -3,10 +3,10 @@ package fractals
 import (
 	"image"
 	"image/color"
-	"image/png"
 	"io"
 
 	"github.com/B3zaleel/fractage/src/helpers"
+	"github.com/llgcode/draw2d"
 	"github.com/llgcode/draw2d/draw2dimg"
 )
 
@@ -21,23 +21,39 @@ type CantorSet struct {
 	Background      color.RGBA
 }
 
-// Writes the Cantor set image to the given output.
-func (props *CantorSet) WriteImage(output io.Writer) {
+// Creates the Cantor set image.
+func (props *CantorSet) CreateImage() (image.Image, error) {
 	viewport := image.Rect(0, 0, props.Width, props.Height)
 	img := image.NewRGBA(viewport)
 	gc := draw2dimg.NewGraphicContext(img)
-	x := float64(viewport.Min.X)
-	y := float64(props.Height)/2 - float64(props.Iterations)*props.LineHeight + props.LineHeight/2
 	helpers.FillImage(img, props.Background)
-	props.render(gc, x, y, float64(viewport.Dx()), props.Iterations)
-	err := png.Encode(output, img)
-	if err != nil {
-		panic(err)
-	}
+	props.draw(gc)
+	return img, nil
+}
+
+// Writes the Cantor set as a PDF document to the given output, whose
+// page has the size of the image at the resolution of the options.
+func (props *CantorSet) WritePDF(output io.Writer, options helpers.PdfOptions) error {
+	pdf, gc := helpers.NewPdfGraphicContext(props.Width, props.Height, props.Background, options)
+	props.draw(gc)
+	return helpers.WritePdf(output, pdf)
+}
+
+// Writes the Cantor set as an SVG document to the given output.
+func (props *CantorSet) WriteSVG(output io.Writer) error {
+	svg, gc := helpers.NewSvgGraphicContext(props.Width, props.Height, props.Background)
+	props.draw(gc)
+	return helpers.WriteSvg(output, svg)
+}
+
+// Draws the Cantor set across the middle of the image.
+func (props *CantorSet) draw(gc draw2d.GraphicContext) {
+	y := float64(props.Height)/2 - float64(props.Iterations)*props.LineHeight + props.LineHeight/2
+	props.render(gc, 0, y, float64(props.Width), props.Iterations)
 }
 
 // Helper function for rendering the Cantor set.
-func (props *CantorSet) render(gc *draw2dimg.GraphicContext, x, y, width float64, level int) {
+func (props *CantorSet) render(gc draw2d.GraphicContext, x, y, width float64, level int) {
 	if level > 0 {
 		dx := width / 3
 		rectColor := props.Color
@@ -0,0 +1,636 @@
+package fractals
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/B3zaleel/fractage/src/helpers"
+)
+
+const (
+	FLAME_MAX_ITERATIONS                = 200_000_000
+	FLAME_DEFAULT_ITERATIONS            = 5_000_000
+	FLAME_MAX_SUPERSAMPLE               = 4
+	FLAME_DEFAULT_SUPERSAMPLE           = 2
+	FLAME_MAX_ESTIMATOR_RADIUS          = 20
+	FLAME_DEFAULT_ESTIMATOR_RADIUS      = 4
+	FLAME_DEFAULT_ESTIMATOR_CURVE       = 0.4
+	FLAME_DEFAULT_GAMMA                 = 4
+	FLAME_DEFAULT_VIBRANCY              = 1
+	FLAME_DEFAULT_BRIGHTNESS            = 1
+	FLAME_DEFAULT_MARGIN                = 10
+	FLAME_DEFAULT_COLOR_PALETTE         = "rainbow"
+	FLAME_DEFAULT_TRANSFORM_WEIGHT      = 1
+	FLAME_DEFAULT_TRANSFORM_COLOR_SPEED = 0.5
+	// The number of iterations skipped before points are plotted.
+	FLAME_FUSE_ITERATIONS = 20
+	// The number of points used for finding the bounds of the flame.
+	FLAME_FOCUS_ITERATIONS = 100_000
+	// The fraction of the points on each side that are left out of the
+	// bounds of the flame.
+	FLAME_FOCUS_QUANTILE = 0.005
+	// The separator of the transforms of a flame.
+	FLAME_TRANSFORM_SEPARATOR = "|"
+	// The number of colors taken from the color palette.
+	FLAME_PALETTE_SIZE       = 256
+	FLAME_DEFAULT_TRANSFORMS = "affine=0.5,0,0,0.5,-0.5,0.5 spherical=0.6 linear=0.4 color=0 | " +
+		"affine=0.5,0,0,0.5,0.5,0.5 swirl=0.3 linear=0.7 color=0.5 | " +
+		"affine=0.5,0,0,0.5,0,-0.5 julia=0.5 horseshoe=0.5 color=1"
+)
+
+var (
+	FLAME_VARIATIONS = map[string]func(x, y float64, rng *rand.Rand) (float64, float64){
+		"linear":       linear_variation,
+		"sinusoidal":   sinusoidal_variation,
+		"spherical":    spherical_variation,
+		"swirl":        swirl_variation,
+		"horseshoe":    horseshoe_variation,
+		"polar":        polar_variation,
+		"handkerchief": handkerchief_variation,
+		"heart":        heart_variation,
+		"disc":         disc_variation,
+		"spiral":       spiral_variation,
+		"hyperbolic":   hyperbolic_variation,
+		"diamond":      diamond_variation,
+		"ex":           ex_variation,
+		"julia":        julia_variation,
+		"bent":         bent_variation,
+		"fisheye":      fisheye_variation,
+		"exponential":  exponential_variation,
+		"power":        power_variation,
+		"cosine":       cosine_variation,
+		"bubble":       bubble_variation,
+		"cylinder":     cylinder_variation,
+		"eyefish":      eyefish_variation,
+		"tangent":      tangent_variation,
+	}
+)
+
+// Represents a weighted variation of a flame transform.
+type FlameVariation struct {
+	Name   string
+	Weight float64
+	Fxn    func(x, y float64, rng *rand.Rand) (float64, float64)
+}
+
+// Represents a transform of a fractal flame.
+type FlameTransform struct {
+	// The affine coefficients a, b, c, d, e and f of the transform, where
+	// x = a*x + b*y + e and y = c*x + d*y + f.
+	Affine [6]float64
+	// The affine coefficients applied after the variations.
+	Post    [6]float64
+	HasPost bool
+	// The relative probability of choosing the transform.
+	Weight float64
+	// The position of the transform in the color palette.
+	Color float64
+	// The rate at which the color of a point moves towards the color of the
+	// transform.
+	ColorSpeed float64
+	Variations []FlameVariation
+}
+
+// Properties of a fractal flame image.
+type Flame struct {
+	Width           int
+	Height          int
+	Transforms      []FlameTransform
+	Final           *FlameTransform
+	Iterations      int
+	Seed            int64
+	Supersample     int
+	EstimatorRadius float64
+	EstimatorCurve  float64
+	Gamma           float64
+	Vibrancy        float64
+	Brightness      float64
+	Focus           bool
+	Region          helpers.Rect
+	Margin          float64
+	ColorPalette    helpers.ColorPalette
+	Background      color.RGBA
+	// The number of bits per channel of the image, which is 8 or 16.
+	BitDepth int
+}
+
+// Represents the accumulated hits and colors of the pixels of a flame.
+type flameHistogram struct {
+	width  int
+	height int
+	counts []float64
+	colors [][3]float64
+}
+
+// Creates the fractal flame image.
+func (props *Flame) CreateImage() (image.Image, error) {
+	viewport := image.Rect(0, 0, props.Width, props.Height)
+	img := helpers.NewImage(viewport, props.BitDepth)
+	helpers.FillImage(img, props.Background)
+	err := props.render(img)
+	if err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+// Helper function for rendering the fractal flame.
+func (props *Flame) render(img draw.RGBA64Image) error {
+	if len(props.Transforms) == 0 {
+		return errors.New("At least one transform is required")
+	}
+	err := props.ColorPalette.TranslateColorTransitions()
+	if err != nil {
+		return err
+	}
+	palette := make([][3]float64, FLAME_PALETTE_SIZE)
+	for i := range palette {
+		paletteColor, err := props.ColorPalette.GetColor(float64(i) / float64(FLAME_PALETTE_SIZE-1))
+		if err != nil {
+			return err
+		}
+		palette[i] = [3]float64{float64(paletteColor.R) / 0xffff, float64(paletteColor.G) / 0xffff, float64(paletteColor.B) / 0xffff}
+	}
+	region := props.Region
+	if props.Focus {
+		region = props.bounds()
+	}
+	supersample := props.Supersample
+	if supersample < 1 {
+		supersample = 1
+	}
+	width, height := props.Width*supersample, props.Height*supersample
+	scale, xOffset, yOffset := helpers.FitRect(region, float64(width), float64(height), props.Margin*float64(supersample))
+	histogram := &flameHistogram{
+		width:  width,
+		height: height,
+		counts: make([]float64, width*height),
+		colors: make([][3]float64, width*height),
+	}
+	props.iterate(props.Iterations, func(x, y, c float64) {
+		ptX := int(xOffset + x*scale)
+		ptY := int(float64(height) - (yOffset + y*scale))
+		if ptX < 0 || ptY < 0 || ptX >= width || ptY >= height {
+			return
+		}
+		i := ptY*width + ptX
+		paletteColor := palette[int(c*float64(FLAME_PALETTE_SIZE-1))]
+		histogram.counts[i]++
+		histogram.colors[i][0] += paletteColor[0]
+		histogram.colors[i][1] += paletteColor[1]
+		histogram.colors[i][2] += paletteColor[2]
+	})
+	if props.EstimatorRadius > 0 {
+		histogram = histogram.estimateDensity(props.EstimatorRadius, props.EstimatorCurve, supersample)
+	}
+	histogram = histogram.downsample(supersample)
+	props.toneMap(img, histogram)
+	return nil
+}
+
+// Runs the chaos game of the flame and passes each plotted point and its
+// position in the color palette to the plot function.
+func (props *Flame) iterate(iterations int, plot func(x, y, c float64)) {
+	rng := rand.New(rand.NewSource(props.Seed))
+	weights := make([]float64, len(props.Transforms))
+	total := 0.0
+	for i, transform := range props.Transforms {
+		total += transform.Weight
+		weights[i] = total
+	}
+	x, y, c := rng.Float64()*2-1, rng.Float64()*2-1, rng.Float64()
+	for i := -FLAME_FUSE_ITERATIONS; i < iterations; i++ {
+		choice := rng.Float64() * total
+		j := sort.SearchFloat64s(weights, choice)
+		if j >= len(weights) {
+			j = len(weights) - 1
+		}
+		x, y, c = props.Transforms[j].apply(x, y, c, rng)
+		if math.IsNaN(x) || math.IsNaN(y) || math.IsInf(x, 0) || math.IsInf(y, 0) {
+			x, y = rng.Float64()*2-1, rng.Float64()*2-1
+			continue
+		}
+		if i < 0 {
+			continue
+		}
+		ptX, ptY, ptC := x, y, c
+		if props.Final != nil {
+			ptX, ptY, ptC = props.Final.apply(x, y, c, rng)
+			if math.IsNaN(ptX) || math.IsNaN(ptY) || math.IsInf(ptX, 0) || math.IsInf(ptY, 0) {
+				continue
+			}
+		}
+		plot(ptX, ptY, ptC)
+	}
+}
+
+// Finds the region of the plane covered by most of the points of the flame.
+func (props *Flame) bounds() helpers.Rect {
+	xs := make([]float64, 0, FLAME_FOCUS_ITERATIONS)
+	ys := make([]float64, 0, FLAME_FOCUS_ITERATIONS)
+	props.iterate(FLAME_FOCUS_ITERATIONS, func(x, y, c float64) {
+		xs = append(xs, x)
+		ys = append(ys, y)
+	})
+	if len(xs) == 0 {
+		return props.Region
+	}
+	sort.Float64s(xs)
+	sort.Float64s(ys)
+	low := int(FLAME_FOCUS_QUANTILE * float64(len(xs)))
+	high := len(xs) - 1 - low
+	return helpers.Rect{
+		X:      xs[low],
+		Y:      ys[low],
+		Width:  xs[high] - xs[low],
+		Height: ys[high] - ys[low],
+	}
+}
+
+// Applies the transform to a point and its position in the color palette.
+func (transform *FlameTransform) apply(x, y, c float64, rng *rand.Rand) (float64, float64, float64) {
+	a := transform.Affine
+	tx := a[0]*x + a[1]*y + a[4]
+	ty := a[2]*x + a[3]*y + a[5]
+	x, y = 0, 0
+	for _, variation := range transform.Variations {
+		vx, vy := variation.Fxn(tx, ty, rng)
+		x += variation.Weight * vx
+		y += variation.Weight * vy
+	}
+	if transform.HasPost {
+		p := transform.Post
+		x, y = p[0]*x+p[1]*y+p[4], p[2]*x+p[3]*y+p[5]
+	}
+	c = c*(1-transform.ColorSpeed) + transform.Color*transform.ColorSpeed
+	return x, y, math.Max(0, math.Min(1, c))
+}
+
+// Spreads the hits of each pixel over a circle whose radius shrinks as the
+// number of hits grows, which smooths the sparse regions of the flame. The
+// radius and the hits are measured in pixels of the image, each of which
+// spans supersample × supersample pixels of the histogram.
+func (histogram *flameHistogram) estimateDensity(maxRadius, curve float64, supersample int) *flameHistogram {
+	samples := float64(supersample * supersample)
+	result := &flameHistogram{
+		width:  histogram.width,
+		height: histogram.height,
+		counts: make([]float64, len(histogram.counts)),
+		colors: make([][3]float64, len(histogram.colors)),
+	}
+	for y := 0; y < histogram.height; y++ {
+		for x := 0; x < histogram.width; x++ {
+			i := y*histogram.width + x
+			count := histogram.counts[i]
+			if count == 0 {
+				continue
+			}
+			radius := float64(supersample) * maxRadius / math.Pow(count*samples, curve)
+			if radius < 1 {
+				result.counts[i] += count
+				result.colors[i][0] += histogram.colors[i][0]
+				result.colors[i][1] += histogram.colors[i][1]
+				result.colors[i][2] += histogram.colors[i][2]
+				continue
+			}
+			r := int(radius)
+			area := 0.0
+			for dy := -r; dy <= r; dy++ {
+				for dx := -r; dx <= r; dx++ {
+					if float64(dx*dx+dy*dy) <= radius*radius {
+						area++
+					}
+				}
+			}
+			for dy := -r; dy <= r; dy++ {
+				for dx := -r; dx <= r; dx++ {
+					nx, ny := x+dx, y+dy
+					if float64(dx*dx+dy*dy) > radius*radius || nx < 0 || ny < 0 || nx >= histogram.width || ny >= histogram.height {
+						continue
+					}
+					j := ny*histogram.width + nx
+					result.counts[j] += count / area
+					result.colors[j][0] += histogram.colors[i][0] / area
+					result.colors[j][1] += histogram.colors[i][1] / area
+					result.colors[j][2] += histogram.colors[i][2] / area
+				}
+			}
+		}
+	}
+	return result
+}
+
+// Sums the hits and colors of each block of factor × factor pixels.
+func (histogram *flameHistogram) downsample(factor int) *flameHistogram {
+	if factor == 1 {
+		return histogram
+	}
+	width, height := histogram.width/factor, histogram.height/factor
+	result := &flameHistogram{
+		width:  width,
+		height: height,
+		counts: make([]float64, width*height),
+		colors: make([][3]float64, width*height),
+	}
+	for y := 0; y < height*factor; y++ {
+		for x := 0; x < width*factor; x++ {
+			i := y*histogram.width + x
+			j := (y/factor)*width + x/factor
+			result.counts[j] += histogram.counts[i]
+			result.colors[j][0] += histogram.colors[i][0]
+			result.colors[j][1] += histogram.colors[i][1]
+			result.colors[j][2] += histogram.colors[i][2]
+		}
+	}
+	return result
+}
+
+// Colors the pixels of the image using the log of their density, where the
+// gamma and vibrancy control how the density brightens the colors.
+func (props *Flame) toneMap(img draw.RGBA64Image, histogram *flameHistogram) {
+	maxCount := 0.0
+	for _, count := range histogram.counts {
+		maxCount = math.Max(maxCount, count)
+	}
+	if maxCount == 0 {
+		return
+	}
+	logMax := math.Log1p(maxCount)
+	background := [3]float64{float64(props.Background.R) / 255, float64(props.Background.G) / 255, float64(props.Background.B) / 255}
+	for y := 0; y < histogram.height; y++ {
+		for x := 0; x < histogram.width; x++ {
+			i := y*histogram.width + x
+			count := histogram.counts[i]
+			if count == 0 {
+				continue
+			}
+			alpha := math.Min(1, props.Brightness*math.Log1p(count)/logMax)
+			gammaAlpha := math.Pow(alpha, 1/props.Gamma)
+			var channels [3]uint16
+			for k := 0; k < 3; k++ {
+				value := histogram.colors[i][k] / count
+				value = props.Vibrancy*gammaAlpha*value + (1-props.Vibrancy)*math.Pow(alpha*value, 1/props.Gamma)
+				value = value + (1-gammaAlpha)*background[k]
+				channels[k] = uint16(math.Max(0, math.Min(1, value)) * 0xffff)
+			}
+			img.SetRGBA64(x, y, color.RGBA64{channels[0], channels[1], channels[2], 0xffff})
+		}
+	}
+}
+
+// Retrieves the transforms of a flame from a list of transforms separated
+// by FLAME_TRANSFORM_SEPARATOR.
+func ParseFlameTransforms(txt string) ([]FlameTransform, error) {
+	var transforms []FlameTransform
+	for i, transformTxt := range strings.Split(txt, FLAME_TRANSFORM_SEPARATOR) {
+		if strings.Trim(transformTxt, helpers.WHITESPACE_CUTSET) == "" {
+			continue
+		}
+		transform, err := ParseFlameTransform(transformTxt)
+		if err != nil {
+			return nil, fmt.Errorf("Transform %d: %s", i+1, err.Error())
+		}
+		transforms = append(transforms, transform)
+	}
+	if len(transforms) == 0 {
+		return nil, errors.New("At least one transform is required")
+	}
+	for i := range transforms {
+		if math.IsNaN(transforms[i].Color) {
+			transforms[i].Color = 0
+			if len(transforms) > 1 {
+				transforms[i].Color = float64(i) / float64(len(transforms)-1)
+			}
+		}
+	}
+	return transforms, nil
+}
+
+// Retrieves a flame transform from a whitespace-separated list of
+// name=value items.
+func ParseFlameTransform(txt string) (FlameTransform, error) {
+	transform := FlameTransform{
+		Affine:     [6]float64{1, 0, 0, 1, 0, 0},
+		Weight:     FLAME_DEFAULT_TRANSFORM_WEIGHT,
+		Color:      math.NaN(),
+		ColorSpeed: FLAME_DEFAULT_TRANSFORM_COLOR_SPEED,
+	}
+	hasAffine := false
+	for _, item := range strings.Fields(txt) {
+		name, value, found := strings.Cut(item, "=")
+		if !found {
+			return transform, fmt.Errorf("Missing value of %s", item)
+		}
+		switch name {
+		case "affine", "post":
+			coefficients, err := parseFlameCoefficients(value)
+			if err != nil {
+				return transform, fmt.Errorf("Invalid %s coefficients: %s", name, err.Error())
+			}
+			if name == "affine" {
+				transform.Affine = coefficients
+				hasAffine = true
+			} else {
+				transform.Post = coefficients
+				transform.HasPost = true
+			}
+		case "weight", "color", "color_speed":
+			number, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return transform, fmt.Errorf("Invalid %s: %s", name, value)
+			}
+			if name == "weight" {
+				if number < 0 {
+					return transform, errors.New("weight must be at least 0")
+				}
+				transform.Weight = number
+			} else if number < 0 || number > 1 {
+				return transform, fmt.Errorf("%s must be between 0 and 1", name)
+			} else if name == "color" {
+				transform.Color = number
+			} else {
+				transform.ColorSpeed = number
+			}
+		default:
+			fxn, ok := FLAME_VARIATIONS[name]
+			if !ok {
+				return transform, fmt.Errorf("Unknown variation %s", name)
+			}
+			weight, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return transform, fmt.Errorf("Invalid weight of %s: %s", name, value)
+			}
+			transform.Variations = append(transform.Variations, FlameVariation{Name: name, Weight: weight, Fxn: fxn})
+		}
+	}
+	if !hasAffine {
+		return transform, errors.New("Missing affine coefficients")
+	}
+	if len(transform.Variations) == 0 {
+		transform.Variations = []FlameVariation{{Name: "linear", Weight: 1, Fxn: linear_variation}}
+	}
+	return transform, nil
+}
+
+// Retrieves the final transform of a flame, which keeps the colors of the
+// points unless its color is given.
+func ParseFlameFinalTransform(txt string) (FlameTransform, error) {
+	transform, err := ParseFlameTransform(txt)
+	if err != nil {
+		return transform, fmt.Errorf("Final transform: %s", err.Error())
+	}
+	if math.IsNaN(transform.Color) {
+		transform.Color = 0
+		transform.ColorSpeed = 0
+	}
+	return transform, nil
+}
+
+// Retrieves the six comma-separated affine coefficients of a flame transform.
+func parseFlameCoefficients(txt string) ([6]float64, error) {
+	var coefficients [6]float64
+	values := strings.Split(txt, ",")
+	if len(values) != len(coefficients) {
+		return coefficients, fmt.Errorf("expected %d values", len(coefficients))
+	}
+	for i, value := range values {
+		number, err := strconv.ParseFloat(strings.Trim(value, helpers.WHITESPACE_CUTSET), 64)
+		if err != nil {
+			return coefficients, err
+		}
+		coefficients[i] = number
+	}
+	return coefficients, nil
+}
+
+func linear_variation(x, y float64, rng *rand.Rand) (float64, float64) {
+	return x, y
+}
+
+func sinusoidal_variation(x, y float64, rng *rand.Rand) (float64, float64) {
+	return math.Sin(x), math.Sin(y)
+}
+
+func spherical_variation(x, y float64, rng *rand.Rand) (float64, float64) {
+	r2 := x*x + y*y
+	return x / r2, y / r2
+}
+
+func swirl_variation(x, y float64, rng *rand.Rand) (float64, float64) {
+	r2 := x*x + y*y
+	sin, cos := math.Sincos(r2)
+	return x*sin - y*cos, x*cos + y*sin
+}
+
+func horseshoe_variation(x, y float64, rng *rand.Rand) (float64, float64) {
+	r := math.Hypot(x, y)
+	return (x - y) * (x + y) / r, 2 * x * y / r
+}
+
+func polar_variation(x, y float64, rng *rand.Rand) (float64, float64) {
+	return math.Atan2(x, y) / math.Pi, math.Hypot(x, y) - 1
+}
+
+func handkerchief_variation(x, y float64, rng *rand.Rand) (float64, float64) {
+	r, theta := math.Hypot(x, y), math.Atan2(x, y)
+	return r * math.Sin(theta+r), r * math.Cos(theta-r)
+}
+
+func heart_variation(x, y float64, rng *rand.Rand) (float64, float64) {
+	r, theta := math.Hypot(x, y), math.Atan2(x, y)
+	return r * math.Sin(theta*r), -r * math.Cos(theta*r)
+}
+
+func disc_variation(x, y float64, rng *rand.Rand) (float64, float64) {
+	r, theta := math.Hypot(x, y), math.Atan2(x, y)
+	sin, cos := math.Sincos(math.Pi * r)
+	return theta / math.Pi * sin, theta / math.Pi * cos
+}
+
+func spiral_variation(x, y float64, rng *rand.Rand) (float64, float64) {
+	r, theta := math.Hypot(x, y), math.Atan2(x, y)
+	return (math.Cos(theta) + math.Sin(r)) / r, (math.Sin(theta) - math.Cos(r)) / r
+}
+
+func hyperbolic_variation(x, y float64, rng *rand.Rand) (float64, float64) {
+	r, theta := math.Hypot(x, y), math.Atan2(x, y)
+	return math.Sin(theta) / r, r * math.Cos(theta)
+}
+
+func diamond_variation(x, y float64, rng *rand.Rand) (float64, float64) {
+	r, theta := math.Hypot(x, y), math.Atan2(x, y)
+	return math.Sin(theta) * math.Cos(r), math.Cos(theta) * math.Sin(r)
+}
+
+func ex_variation(x, y float64, rng *rand.Rand) (float64, float64) {
+	r, theta := math.Hypot(x, y), math.Atan2(x, y)
+	p0, p1 := math.Sin(theta+r), math.Cos(theta-r)
+	p0, p1 = p0*p0*p0, p1*p1*p1
+	return r * (p0 + p1), r * (p0 - p1)
+}
+
+func julia_variation(x, y float64, rng *rand.Rand) (float64, float64) {
+	r, theta := math.Sqrt(math.Hypot(x, y)), math.Atan2(x, y)/2
+	if rng.Intn(2) == 1 {
+		theta += math.Pi
+	}
+	return r * math.Cos(theta), r * math.Sin(theta)
+}
+
+func bent_variation(x, y float64, rng *rand.Rand) (float64, float64) {
+	if x < 0 {
+		x *= 2
+	}
+	if y < 0 {
+		y /= 2
+	}
+	return x, y
+}
+
+func fisheye_variation(x, y float64, rng *rand.Rand) (float64, float64) {
+	r := 2 / (math.Hypot(x, y) + 1)
+	return r * y, r * x
+}
+
+func exponential_variation(x, y float64, rng *rand.Rand) (float64, float64) {
+	e := math.Exp(x - 1)
+	sin, cos := math.Sincos(math.Pi * y)
+	return e * cos, e * sin
+}
+
+func power_variation(x, y float64, rng *rand.Rand) (float64, float64) {
+	r, theta := math.Hypot(x, y), math.Atan2(x, y)
+	sin, cos := math.Sincos(theta)
+	p := math.Pow(r, sin)
+	return p * cos, p * sin
+}
+
+func cosine_variation(x, y float64, rng *rand.Rand) (float64, float64) {
+	return math.Cos(math.Pi*x) * math.Cosh(y), -math.Sin(math.Pi*x) * math.Sinh(y)
+}
+
+func bubble_variation(x, y float64, rng *rand.Rand) (float64, float64) {
+	r := 4 / (x*x + y*y + 4)
+	return r * x, r * y
+}
+
+func cylinder_variation(x, y float64, rng *rand.Rand) (float64, float64) {
+	return math.Sin(x), y
+}
+
+func eyefish_variation(x, y float64, rng *rand.Rand) (float64, float64) {
+	r := 2 / (math.Hypot(x, y) + 1)
+	return r * x, r * y
+}
+
+func tangent_variation(x, y float64, rng *rand.Rand) (float64, float64) {
+	return math.Sin(x) / math.Cos(y), math.Tan(y)
+}
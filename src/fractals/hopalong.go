@@ -1,24 +1,104 @@
 package fractals
 
 import (
+	"errors"
+	"fmt"
 	"image"
 	"image/color"
-	"image/png"
-	"io"
+	"image/draw"
 	"math"
+	"strings"
 
 	"github.com/B3zaleel/fractage/src/helpers"
 )
 
+const (
+	HOPALONG_COLORING_SOLID     = "solid"
+	HOPALONG_COLORING_DENSITY   = "density"
+	HOPALONG_COLORING_ITERATION = "iteration"
+	// The number of random colors used for the solid coloring.
+	HOPALONG_RANDOM_COLORS_COUNT = 28
+)
+
 var (
-	HOPALONG_TYPES = map[string]func(props *Hopalong, xIn, yIn float64) (xOut, yOut float64){
-		"classic_bm":      classic_barry_martin_fractal,
-		"positive_bm":     positive_barry_martin_fractal,
-		"additive_bm":     additive_barry_martin_fractal,
-		"gingerbread_man": gingerbread_man_fractal,
+	HOPALONG_TYPES = map[string]HopalongType{
+		"classic_bm": {
+			Parameters: []string{"a", "b", "c"},
+			Defaults:   []float64{5, 1, 5},
+			Start:      helpers.Point{X: -1, Y: 0},
+			Fxn:        classic_barry_martin_fractal,
+		},
+		"positive_bm": {
+			Parameters: []string{"a", "b", "c"},
+			Defaults:   []float64{5, 1, 5},
+			Start:      helpers.Point{X: -1, Y: 0},
+			Fxn:        positive_barry_martin_fractal,
+		},
+		"additive_bm": {
+			Parameters: []string{"a", "b", "c"},
+			Defaults:   []float64{5, 1, 5},
+			Start:      helpers.Point{X: -1, Y: 0},
+			Fxn:        additive_barry_martin_fractal,
+		},
+		"gingerbread_man": {
+			Parameters: []string{"a", "b"},
+			Defaults:   []float64{1, 1},
+			Start:      helpers.Point{X: -0.1, Y: 0},
+			Fxn:        gingerbread_man_fractal,
+		},
+		"three_ply": {
+			Parameters: []string{"a", "b", "c"},
+			Defaults:   []float64{-55, -1, -42},
+			Start:      helpers.Point{X: 0, Y: 0},
+			Fxn:        three_ply_fractal,
+		},
+		"quadrup_two": {
+			Parameters: []string{"a", "b", "c"},
+			Defaults:   []float64{34, 1, 5},
+			Start:      helpers.Point{X: 0, Y: 0},
+			Fxn:        quadrup_two_fractal,
+		},
+		"chip": {
+			Parameters: []string{"a", "b", "c"},
+			Defaults:   []float64{-15, -19, 1},
+			Start:      helpers.Point{X: 0, Y: 0},
+			Fxn:        chip_fractal,
+		},
+		"sine_hopalong": {
+			Parameters: []string{"a", "b", "c", "d"},
+			Defaults:   []float64{5, 1, 5, 1},
+			Start:      helpers.Point{X: -1, Y: 0},
+			Fxn:        sine_hopalong_fractal,
+		},
+		"martin_sine": {
+			Parameters: []string{"a", "b", "d"},
+			Defaults:   []float64{3.14, 1, 1},
+			Start:      helpers.Point{X: 0, Y: 0},
+			Fxn:        martin_sine_fractal,
+		},
+		"martin_cosine": {
+			Parameters: []string{"a", "b", "d"},
+			Defaults:   []float64{0.01, 1, 1},
+			Start:      helpers.Point{X: -1.5, Y: -1.5},
+			Fxn:        martin_cosine_fractal,
+		},
 	}
+	// The names of the parameters that can be used by a hopalong function.
+	HOPALONG_PARAMETERS = []string{"a", "b", "c", "d"}
 )
 
+// Represents a hopalong function.
+type HopalongType struct {
+	// The names of the parameters used by the function.
+	Parameters []string
+	// The default values of the parameters used by the function.
+	Defaults []float64
+	// The default starting point of the function.
+	Start helpers.Point
+	// Computes the next point of the function.
+	Fxn func(props *Hopalong, xIn, yIn float64) (xOut, yOut float64)
+}
+
 // Properties of a Hopalong image.
 type Hopalong struct {
 	Width           int
@@ -34,41 +114,155 @@ type Hopalong struct {
 	Type            string
 	Scale           float64
 	Resolution      int
-	Background      color.RGBA
+	Iterations      int
+	Focus           bool
+	Margin          float64
+	Coloring        string
+	ColorPalette    helpers.ColorPalette
+	ToneMapping     string
+	Gamma           float64
+	// The rotations and reflections that copy each point of the orbit
+	// around the symmetry center.
+	Symmetry       helpers.Symmetry
+	SymmetryCenter helpers.Point
+	Background     color.RGBA
+	// The number of bits per channel of the image, which is 8 or 16.
+	BitDepth int
 }
 
-// Writes the Hopalong image to the given output.
-func (props *Hopalong) WriteImage(output io.Writer) {
+// Creates the Hopalong image.
+func (props *Hopalong) CreateImage() (image.Image, error) {
 	viewport := image.Rect(0, 0, props.Width, props.Height)
-	img := image.NewRGBA(viewport)
+	img := helpers.NewImage(viewport, props.BitDepth)
 	helpers.FillImage(img, props.Background)
-	props.render(img)
-	err := png.Encode(output, img)
+	err := props.render(img)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
+	return img, nil
 }
 
 // Helper function for rendering the Hopalong.
-func (props *Hopalong) render(img *image.RGBA) {
-	x, y := props.X, props.Y
-	midX, midY := float64(props.Width)/2.0, float64(props.Height)/2.0
-	ptColor := props.Color
-	if props.UseRandomColors {
-		ptColor = helpers.RandomColor()
+func (props *Hopalong) render(img draw.RGBA64Image) error {
+	iterations := props.Iterations
+	if iterations <= 0 {
+		iterations = props.Width * props.Height * props.Resolution
+	}
+	scale := props.Scale
+	xOffset, yOffset := float64(props.Width)/2.0, float64(props.Height)/2.0
+	xMin, yMin := math.Inf(1), math.Inf(1)
+	xMax, yMax := math.Inf(-1), math.Inf(-1)
+	ptColor := helpers.ToRGBA64Color(props.Color)
+	colorPeriod := iterations/HOPALONG_RANDOM_COLORS_COUNT + 1
+	var density *helpers.DensityBuffer
+	if props.Coloring == HOPALONG_COLORING_DENSITY {
+		density = helpers.NewDensityBuffer(props.Width, props.Height)
+	} else if props.Coloring == HOPALONG_COLORING_ITERATION {
+		err := props.ColorPalette.TranslateColorTransitions()
+		if err != nil {
+			return err
+		}
 	}
-	hopalong_fxn := HOPALONG_TYPES[props.Type]
-	for i := 0; i < props.Width; i++ {
-		for j := 0; j < props.Height; j++ {
-			for k := 0; k < props.Resolution; k++ {
-				x, y = hopalong_fxn(props, x, y)
-				if props.UseRandomColors && i%50 == 0 {
-					ptColor = helpers.RandomColor()
+	hopalongType, ok := HOPALONG_TYPES[props.Type]
+	if !ok {
+		return fmt.Errorf("Invalid hopalong type: %s", props.Type)
+	}
+	hopalong_fxn := hopalongType.Fxn
+	for round := 1; round < 3; round++ {
+		x, y := props.X, props.Y
+		if props.Focus && round == 2 {
+			bounds := helpers.Rect{X: xMin, Y: yMin, Width: xMax - xMin, Height: yMax - yMin}
+			if math.IsInf(bounds.Width, 0) || math.IsInf(bounds.Height, 0) {
+				return errors.New("The orbit of the hopalong diverges before it can be fitted to the image")
+			}
+			scale, xOffset, yOffset = helpers.FitRect(bounds, float64(props.Width), float64(props.Height), props.Margin)
+		}
+		for i := 0; i < iterations; i++ {
+			x, y = hopalong_fxn(props, x, y)
+			// an orbit that has diverged never comes back
+			if math.IsNaN(x) || math.IsNaN(y) || math.IsInf(x, 0) || math.IsInf(y, 0) {
+				break
+			}
+			if props.Focus && round == 1 {
+				props.Symmetry.Images(x, y, props.SymmetryCenter, func(x, y float64) {
+					xMin = math.Min(xMin, x)
+					yMin = math.Min(yMin, y)
+					xMax = math.Max(xMax, x)
+					yMax = math.Max(yMax, y)
+				})
+				continue
+			}
+			switch props.Coloring {
+			case HOPALONG_COLORING_ITERATION:
+				iterationColor, err := props.ColorPalette.GetColor(float64(i) / float64(iterations))
+				if err != nil {
+					return err
+				}
+				ptColor = iterationColor
+			case HOPALONG_COLORING_SOLID:
+				if props.UseRandomColors && i%colorPeriod == 0 {
+					ptColor = helpers.ToRGBA64Color(helpers.RandomColor())
 				}
-				img.Set(int(midX+x*props.Scale), int(midY-y*props.Scale), ptColor)
 			}
+			props.Symmetry.Images(x, y, props.SymmetryCenter, func(x, y float64) {
+				ptX := int(xOffset + x*scale)
+				ptY := int(float64(props.Height) - (yOffset + y*scale))
+				if density != nil {
+					density.Hit(ptX, ptY)
+				} else {
+					img.SetRGBA64(ptX, ptY, ptColor)
+				}
+			})
+		}
+		if !props.Focus {
+			break
 		}
 	}
+	if density != nil {
+		return density.ToneMap(img, props.ColorPalette, props.ToneMapping, props.Gamma)
+	}
+	return nil
+}
+
+// Checks if a coloring name is supported by the Hopalong.
+func IsValidHopalongColoring(txt string) bool {
+	return txt == HOPALONG_COLORING_SOLID || txt == HOPALONG_COLORING_DENSITY || txt == HOPALONG_COLORING_ITERATION
+}
+
+// Checks if a type name exists in the set of HOPALONG_TYPES names.
+func IsValidHopalongType(txt string) bool {
+	typeName := strings.Trim(txt, helpers.WHITESPACE_CUTSET)
+	for name := range HOPALONG_TYPES {
+		if name == typeName {
+			return true
+		}
+	}
+	return false
+}
+
+// Retrieves the value of a parameter of a hopalong function by its name.
+func (props *Hopalong) Parameter(name string) *float64 {
+	switch name {
+	case "a":
+		return &props.A
+	case "b":
+		return &props.B
+	case "c":
+		return &props.C
+	case "d":
+		return &props.D
+	}
+	return nil
+}
+
+// Computes the sign of a number as -1, 0 or 1.
+func sign(x float64) float64 {
+	if x < 0 {
+		return -1
+	} else if x > 0 {
+		return 1
+	}
+	return 0
 }
 
 func classic_barry_martin_fractal(props *Hopalong, xIn, yIn float64) (xOut, yOut float64) {
@@ -106,3 +300,48 @@ func gingerbread_man_fractal(props *Hopalong, xIn, yIn float64) (xOut, yOut floa
 	yOut = float64(props.A) - xIn
 	return
 }
+
+func three_ply_fractal(props *Hopalong, xIn, yIn float64) (xOut, yOut float64) {
+	a, b, c := props.A, props.B, props.C
+	xOut = yIn - sign(xIn)*math.Abs(math.Sin(xIn)*math.Cos(b)+c-xIn*math.Sin(a+b+c))
+	yOut = a - xIn
+	return
+}
+
+func quadrup_two_fractal(props *Hopalong, xIn, yIn float64) (xOut, yOut float64) {
+	a, b, c := props.A, props.B, props.C
+	logCX := math.Log(math.Abs(c*xIn - b))
+	xOut = yIn - sign(xIn)*math.Sin(math.Log(math.Abs(b*xIn-c)))*math.Atan(logCX*logCX)
+	yOut = a - xIn
+	return
+}
+
+func chip_fractal(props *Hopalong, xIn, yIn float64) (xOut, yOut float64) {
+	a, b, c := props.A, props.B, props.C
+	logBX := math.Log(math.Abs(b*xIn - c))
+	logCX := math.Log(math.Abs(c*xIn - b))
+	xOut = yIn - sign(xIn)*math.Cos(logBX*logBX)*math.Atan(logCX*logCX)
+	yOut = a - xIn
+	return
+}
+
+func sine_hopalong_fractal(props *Hopalong, xIn, yIn float64) (xOut, yOut float64) {
+	a, b, c, d := props.A, props.B, props.C, props.D
+	xOut = yIn - sign(xIn)*math.Sqrt(math.Abs(b*xIn-c)) + d*math.Sin(xIn)
+	yOut = a - xIn
+	return
+}
+
+func martin_sine_fractal(props *Hopalong, xIn, yIn float64) (xOut, yOut float64) {
+	a, b, d := props.A, props.B, props.D
+	xOut = yIn - d*math.Sin(b*xIn)
+	yOut = a - xIn
+	return
+}
+
+func martin_cosine_fractal(props *Hopalong, xIn, yIn float64) (xOut, yOut float64) {
+	a, b, d := props.A, props.B, props.D
+	xOut = yIn - d*math.Cos(b*xIn)
+	yOut = a - xIn
+	return
+}
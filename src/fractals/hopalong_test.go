@@ -0,0 +1,48 @@
+package fractals
+
+import (
+	"image/color"
+	"math"
+	"testing"
+)
+
+// Creates a gingerbread man Hopalong, whose orbit grows without bound when b
+// is greater than 1.
+func testHopalong(a, b float64, focus bool) Hopalong {
+	return Hopalong{
+		Width:      64,
+		Height:     64,
+		Color:      color.RGBA{255, 255, 255, 255},
+		A:          a,
+		B:          b,
+		X:          -0.1,
+		Type:       "gingerbread_man",
+		Scale:      5,
+		Iterations: 10_000,
+		Focus:      focus,
+		Coloring:   HOPALONG_COLORING_SOLID,
+		BitDepth:   8,
+	}
+}
+
+func TestHopalongDivergingOrbit(t *testing.T) {
+	tests := []struct {
+		name      string
+		fractal   Hopalong
+		canRender bool
+	}{
+		{"converging focused", testHopalong(1, 1, true), true},
+		{"diverging", testHopalong(1, 4, false), true},
+		{"diverging focused", testHopalong(1, 4, true), true},
+		{"no finite points", testHopalong(math.NaN(), 1, false), true},
+		{"no finite points focused", testHopalong(math.NaN(), 1, true), false},
+	}
+	for _, test := range tests {
+		_, err := test.fractal.CreateImage()
+		if test.canRender && err != nil {
+			t.Errorf("%s: got error %q", test.name, err)
+		} else if !test.canRender && err == nil {
+			t.Errorf("%s: got no error", test.name)
+		}
+	}
+}
@@ -2,13 +2,14 @@ package fractals
 
 import (
 	"errors"
+	"fmt"
 	"image"
 	"image/color"
-	"image/png"
-	"io"
+	"image/draw"
 	"math"
 	"math/rand"
 	"strconv"
+	"strings"
 
 	"github.com/B3zaleel/fractage/src/helpers"
 )
@@ -23,6 +24,22 @@ const (
 	IFS_FXN_INDEX_PROBABILITY = 6
 	// The number of variables in each set of the iterated function system.
 	IFS_FXN_VARIABLES_COUNT = int(7)
+	// The separator of the transforms of an iterated function system.
+	IFS_TRANSFORM_SEPARATOR = "|"
+	// The largest difference between 1 and the sum of the probabilities.
+	IFS_PROBABILITY_TOLERANCE = 0.01
+	// The smallest absolute determinant used for deriving a probability, so
+	// that transforms that flatten the plane are still chosen.
+	IFS_MIN_DETERMINANT = 0.01
+	// The number of points used for finding the bounds of the attractor.
+	IFS_BOUNDS_ITERATIONS = 100_000
+
+	IFS_METHOD_CHAOS         = "chaos"
+	IFS_METHOD_DETERMINISTIC = "deterministic"
+	IFS_METHOD_ESCAPE        = "escape"
+	IFS_SHAPE_SQUARE         = "square"
+	IFS_SHAPE_CIRCLE         = "circle"
+	IFS_SHAPE_POINT          = "point"
 )
 
 // Properties of an iterated function system (IFS) image.
@@ -36,24 +53,47 @@ type IteratedFunctionSystem struct {
 	Scale      float64
 	Focus      bool
 	Variables  [][IFS_FXN_VARIABLES_COUNT]float64
-	Background color.RGBA
+	// The method for drawing the attractor (chaos, deterministic or escape).
+	Method string
+	// The number of generations of the deterministic method or the maximum
+	// number of inverse iterations of the escape method.
+	Generations int
+	// The starting shape of the deterministic method.
+	Shape string
+	// The rotations and reflections that are added to each set of the IFS
+	// around the symmetry center.
+	Symmetry       helpers.Symmetry
+	SymmetryCenter helpers.Point
+	Background     color.RGBA
+	// The number of bits per channel of the image, which is 8 or 16.
+	BitDepth int
 }
 
-// Writes the IFS image to the given output.
-func (props *IteratedFunctionSystem) WriteImage(output io.Writer) error {
+// Creates the IFS image.
+func (props *IteratedFunctionSystem) CreateImage() (image.Image, error) {
 	viewport := image.Rect(0, 0, props.Width, props.Height)
-	img := image.NewRGBA(viewport)
+	img := helpers.NewImage(viewport, props.BitDepth)
 	helpers.FillImage(img, props.Background)
-	props.render(img)
-	err := png.Encode(output, img)
+	if !props.Symmetry.IsTrivial() {
+		props.addSymmetricSets()
+	}
+	var err error
+	switch props.Method {
+	case IFS_METHOD_DETERMINISTIC:
+		props.renderDeterministic(img)
+	case IFS_METHOD_ESCAPE:
+		err = props.renderEscape(img)
+	default:
+		props.render(img)
+	}
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return nil
+	return img, nil
 }
 
 // Helper function for rendering the IFS.
-func (props *IteratedFunctionSystem) render(img *image.RGBA) {
+func (props *IteratedFunctionSystem) render(img draw.RGBA64Image) {
 	xMin, yMin, xMax, yMax := 0.0, 0.0, 0.0, 0.0
 	var x, y, xn float64
 	var ptColor color.RGBA
@@ -80,7 +120,7 @@ func (props *IteratedFunctionSystem) render(img *image.RGBA) {
 			for j := 0; j < len(props.Variables); j++ {
 				fxn := props.Variables[j]
 				sum += fxn[IFS_FXN_INDEX_PROBABILITY]
-				if probability <= sum {
+				if probability <= sum || j == len(props.Variables)-1 {
 					a := fxn[IFS_FXN_INDEX_A]
 					b := fxn[IFS_FXN_INDEX_B]
 					c := fxn[IFS_FXN_INDEX_C]
@@ -111,6 +151,214 @@ func (props *IteratedFunctionSystem) render(img *image.RGBA) {
 	}
 }
 
+// Adds a copy of each set of the IFS for each element of its symmetry group,
+// which makes the attractor symmetric. The copies share the colors and the
+// probabilities of their sets.
+func (props *IteratedFunctionSystem) addSymmetricSets() {
+	matrices := props.Symmetry.Matrices()
+	count := len(props.Variables)
+	variables := make([][IFS_FXN_VARIABLES_COUNT]float64, 0, count*len(matrices))
+	colors := make([]color.RGBA, 0, count*len(matrices))
+	cx, cy := props.SymmetryCenter.X, props.SymmetryCenter.Y
+	for _, m := range matrices {
+		// the element maps p to m*p + t, which keeps the center in place
+		tx := cx - (m[0]*cx + m[1]*cy)
+		ty := cy - (m[2]*cx + m[3]*cy)
+		for i, fxn := range props.Variables {
+			a, b := fxn[IFS_FXN_INDEX_A], fxn[IFS_FXN_INDEX_B]
+			c, d := fxn[IFS_FXN_INDEX_C], fxn[IFS_FXN_INDEX_D]
+			e, f := fxn[IFS_FXN_INDEX_E], fxn[IFS_FXN_INDEX_F]
+			var symmetricFxn [IFS_FXN_VARIABLES_COUNT]float64
+			symmetricFxn[IFS_FXN_INDEX_A] = m[0]*a + m[1]*c
+			symmetricFxn[IFS_FXN_INDEX_B] = m[0]*b + m[1]*d
+			symmetricFxn[IFS_FXN_INDEX_C] = m[2]*a + m[3]*c
+			symmetricFxn[IFS_FXN_INDEX_D] = m[2]*b + m[3]*d
+			symmetricFxn[IFS_FXN_INDEX_E] = m[0]*e + m[1]*f + tx
+			symmetricFxn[IFS_FXN_INDEX_F] = m[2]*e + m[3]*f + ty
+			symmetricFxn[IFS_FXN_INDEX_PROBABILITY] = fxn[IFS_FXN_INDEX_PROBABILITY] / float64(len(matrices))
+			variables = append(variables, symmetricFxn)
+			colors = append(colors, props.Colors[i])
+		}
+	}
+	props.Variables = variables
+	props.Colors = colors
+}
+
+// Helper function for rendering the IFS by applying every set to a starting
+// set of pixels for a number of generations.
+func (props *IteratedFunctionSystem) renderDeterministic(img draw.RGBA64Image) {
+	if props.Focus {
+		props.fitBounds()
+	}
+	width, height := props.Width, props.Height
+	// owners[i] is 1 + the index of the set that last drew pixel i, or 0 if
+	// pixel i isn't in the set of pixels
+	owners := make([]int, width*height)
+	centerX, centerY := float64(width)/2, float64(height)/2
+	radius := math.Min(centerX, centerY)
+	for py := 0; py < height; py++ {
+		for px := 0; px < width; px++ {
+			dx, dy := float64(px)+0.5-centerX, float64(py)+0.5-centerY
+			switch props.Shape {
+			case IFS_SHAPE_CIRCLE:
+				if dx*dx+dy*dy <= radius*radius {
+					owners[py*width+px] = 1
+				}
+			case IFS_SHAPE_POINT:
+				if px == int(centerX) && py == int(centerY) {
+					owners[py*width+px] = 1
+				}
+			default:
+				owners[py*width+px] = 1
+			}
+		}
+	}
+	for generation := 0; generation < props.Generations; generation++ {
+		next := make([]int, width*height)
+		for py := 0; py < height; py++ {
+			for px := 0; px < width; px++ {
+				if owners[py*width+px] == 0 {
+					continue
+				}
+				x := (float64(px) + 0.5 - props.X) / props.Scale
+				y := (float64(height) - float64(py) - 0.5 - props.Y) / props.Scale
+				for j, fxn := range props.Variables {
+					xn := fxn[IFS_FXN_INDEX_A]*x + fxn[IFS_FXN_INDEX_B]*y + fxn[IFS_FXN_INDEX_E]
+					yn := fxn[IFS_FXN_INDEX_C]*x + fxn[IFS_FXN_INDEX_D]*y + fxn[IFS_FXN_INDEX_F]
+					ptX := int(math.Floor(props.X + xn*props.Scale))
+					ptY := int(math.Floor(float64(height) - (props.Y + yn*props.Scale)))
+					if ptX >= 0 && ptY >= 0 && ptX < width && ptY < height {
+						next[ptY*width+ptX] = j + 1
+					}
+				}
+			}
+		}
+		owners = next
+	}
+	for i, owner := range owners {
+		if owner > 0 {
+			img.Set(i%width, i/width, props.Colors[owner-1])
+		}
+	}
+}
+
+// Helper function for rendering the IFS by coloring each point of the
+// plane by the number of inverse iterations that keep it near the attractor.
+// Each point is colored with the set whose inverse first moves it towards
+// the attractor.
+func (props *IteratedFunctionSystem) renderEscape(img draw.RGBA64Image) error {
+	xMin, yMin, xMax, yMax := props.bounds()
+	if props.Focus {
+		props.fitBounds()
+	}
+	// the inverses of the invertible sets, where inverses[j] is nil if set
+	// j isn't invertible
+	inverses := make([]*[6]float64, len(props.Variables))
+	invertible := false
+	for j, fxn := range props.Variables {
+		a, b := fxn[IFS_FXN_INDEX_A], fxn[IFS_FXN_INDEX_B]
+		c, d := fxn[IFS_FXN_INDEX_C], fxn[IFS_FXN_INDEX_D]
+		e, f := fxn[IFS_FXN_INDEX_E], fxn[IFS_FXN_INDEX_F]
+		det := a*d - b*c
+		if math.Abs(det) < 1e-12 {
+			continue
+		}
+		ia, ib, ic, id := d/det, -b/det, -c/det, a/det
+		inverses[j] = &[6]float64{ia, ib, ic, id, -(ia*e + ib*f), -(ic*e + id*f)}
+		invertible = true
+	}
+	if !invertible {
+		return errors.New("The escape method requires at least one invertible transform")
+	}
+	centerX, centerY := (xMin+xMax)/2, (yMin+yMax)/2
+	escapeRadius := math.Max(math.Hypot(xMax-xMin, yMax-yMin), 1e-9)
+	for py := 0; py < props.Height; py++ {
+		for px := 0; px < props.Width; px++ {
+			x := (float64(px) + 0.5 - props.X) / props.Scale
+			y := (float64(props.Height) - float64(py) - 0.5 - props.Y) / props.Scale
+			count, first := 0, -1
+			for ; count < props.Generations; count++ {
+				if math.Hypot(x-centerX, y-centerY) > escapeRadius {
+					break
+				}
+				closest, closestX, closestY := -1, 0.0, 0.0
+				closestDistance := math.Inf(1)
+				for j, inverse := range inverses {
+					if inverse == nil {
+						continue
+					}
+					xn := inverse[0]*x + inverse[1]*y + inverse[4]
+					yn := inverse[2]*x + inverse[3]*y + inverse[5]
+					distance := math.Hypot(xn-centerX, yn-centerY)
+					if distance < closestDistance {
+						closest, closestX, closestY, closestDistance = j, xn, yn, distance
+					}
+				}
+				if first < 0 {
+					first = closest
+				}
+				x, y = closestX, closestY
+			}
+			if count == 0 {
+				continue
+			}
+			t := float64(count) / float64(props.Generations)
+			setColor := props.Colors[first]
+			img.SetRGBA64(px, py, color.RGBA64{
+				uint16(0x101 * (float64(props.Background.R) + t*(float64(setColor.R)-float64(props.Background.R)))),
+				uint16(0x101 * (float64(props.Background.G) + t*(float64(setColor.G)-float64(props.Background.G)))),
+				uint16(0x101 * (float64(props.Background.B) + t*(float64(setColor.B)-float64(props.Background.B)))),
+				0xffff,
+			})
+		}
+	}
+	return nil
+}
+
+// Finds the bounds of the attractor of the IFS using the chaos game.
+func (props *IteratedFunctionSystem) bounds() (xMin, yMin, xMax, yMax float64) {
+	rng := rand.New(rand.NewSource(0))
+	var x, y float64
+	for i := 0; i < IFS_BOUNDS_ITERATIONS; i++ {
+		probability := rng.Float64()
+		sum := 0.0
+		for j, fxn := range props.Variables {
+			sum += fxn[IFS_FXN_INDEX_PROBABILITY]
+			if probability <= sum || j == len(props.Variables)-1 {
+				x, y = fxn[IFS_FXN_INDEX_A]*x+fxn[IFS_FXN_INDEX_B]*y+fxn[IFS_FXN_INDEX_E],
+					fxn[IFS_FXN_INDEX_C]*x+fxn[IFS_FXN_INDEX_D]*y+fxn[IFS_FXN_INDEX_F]
+				break
+			}
+		}
+		xMin = math.Min(xMin, x)
+		yMin = math.Min(yMin, y)
+		xMax = math.Max(xMax, x)
+		yMax = math.Max(yMax, y)
+	}
+	return xMin, yMin, xMax, yMax
+}
+
+// Sets the scale and displacement of the image so that the attractor of
+// the IFS fills the image.
+func (props *IteratedFunctionSystem) fitBounds() {
+	xMin, yMin, xMax, yMax := props.bounds()
+	boundsWidth, boundsHeight := xMax-xMin, yMax-yMin
+	xScale, yScale := float64(props.Width)/boundsWidth, float64(props.Height)/boundsHeight
+	props.Scale = math.Min(xScale, yScale)
+	props.X = -xMin*props.Scale + (float64(props.Width)-boundsWidth*props.Scale)/2
+	props.Y = -yMin*props.Scale + (float64(props.Height)-boundsHeight*props.Scale)/2
+}
+
+// Checks if a method is supported by the IFS.
+func IsValidIFSMethod(txt string) bool {
+	return txt == IFS_METHOD_CHAOS || txt == IFS_METHOD_DETERMINISTIC || txt == IFS_METHOD_ESCAPE
+}
+
+// Checks if a starting shape is supported by the deterministic method.
+func IsValidIFSShape(txt string) bool {
+	return txt == IFS_SHAPE_SQUARE || txt == IFS_SHAPE_CIRCLE || txt == IFS_SHAPE_POINT
+}
+
 // Retrieves a comma-separated list of the variables for each set of the IFS.
 func GetIFSVariables(txt string) ([][IFS_FXN_VARIABLES_COUNT]float64, error) {
 	values, err := helpers.GetCSV(txt)
@@ -160,9 +408,235 @@ func GetIFSVariables(txt string) ([][IFS_FXN_VARIABLES_COUNT]float64, error) {
 		functions[j][IFS_FXN_INDEX_PROBABILITY] = probability
 		j++
 	}
+	err = ValidateIFSVariables(functions)
+	if err != nil {
+		return nil, err
+	}
 	return functions, nil
 }
 
+// Retrieves the variables for each set of the IFS from a list of transforms
+// separated by IFS_TRANSFORM_SEPARATOR.
+func ParseIFSTransforms(txt string) ([][IFS_FXN_VARIABLES_COUNT]float64, error) {
+	var functions [][IFS_FXN_VARIABLES_COUNT]float64
+	for _, transformTxt := range strings.Split(txt, IFS_TRANSFORM_SEPARATOR) {
+		if strings.Trim(transformTxt, helpers.WHITESPACE_CUTSET) == "" {
+			continue
+		}
+		fxn, err := ParseIFSTransform(transformTxt)
+		if err != nil {
+			return nil, fmt.Errorf("Transform %d: %s", len(functions)+1, err.Error())
+		}
+		functions = append(functions, fxn)
+	}
+	if len(functions) == 0 {
+		return nil, errors.New("At least one transform is required")
+	}
+	err := ValidateIFSContraction(functions)
+	if err != nil {
+		return nil, err
+	}
+	err = ValidateIFSVariables(functions)
+	if err != nil {
+		return nil, err
+	}
+	return functions, nil
+}
+
+// Retrieves the variables of a set of the IFS from a whitespace-separated
+// list of name=value items. The probability is NaN when it isn't given.
+func ParseIFSTransform(txt string) ([IFS_FXN_VARIABLES_COUNT]float64, error) {
+	var fxn [IFS_FXN_VARIABLES_COUNT]float64
+	values := map[string][]float64{}
+	for _, item := range strings.Fields(txt) {
+		name, value, found := strings.Cut(item, "=")
+		if !found {
+			return fxn, fmt.Errorf("Missing value of %s", item)
+		}
+		if _, ok := values[name]; ok {
+			return fxn, fmt.Errorf("%s is given more than once", name)
+		}
+		numbers, err := helpers.GetCSV(value)
+		if err != nil {
+			return fxn, err
+		}
+		values[name] = make([]float64, len(numbers))
+		for i, number := range numbers {
+			values[name][i], err = strconv.ParseFloat(number, 64)
+			if err != nil {
+				return fxn, fmt.Errorf("Invalid %s: %s", name, value)
+			}
+		}
+	}
+	counts := map[string][]int{
+		"affine":      {6},
+		"translate":   {2},
+		"rotate":      {1},
+		"scale":       {1, 2},
+		"shear":       {1, 2},
+		"from":        {6},
+		"to":          {6},
+		"probability": {1},
+	}
+	for name, numbers := range values {
+		allowed, ok := counts[name]
+		if !ok {
+			return fxn, fmt.Errorf("Unknown item %s", name)
+		}
+		if len(allowed) > 1 && len(numbers) != allowed[0] && len(numbers) != allowed[1] {
+			return fxn, fmt.Errorf("%s must have %d or %d values", name, allowed[0], allowed[1])
+		} else if len(allowed) == 1 && len(numbers) != allowed[0] {
+			return fxn, fmt.Errorf("%s must have %d values", name, allowed[0])
+		}
+	}
+	_, hasAffine := values["affine"]
+	_, hasTo := values["to"]
+	_, hasFrom := values["from"]
+	hasComponents := false
+	for _, name := range []string{"translate", "rotate", "scale", "shear"} {
+		if _, ok := values[name]; ok {
+			hasComponents = true
+		}
+	}
+	if hasFrom && !hasTo {
+		return fxn, errors.New("from requires to")
+	}
+	if (hasAffine && (hasTo || hasComponents)) || (hasTo && hasComponents) {
+		return fxn, errors.New("Only one of affine, components or a mapping of points can be given")
+	}
+	var a, b, c, d, e, f float64
+	if hasAffine {
+		affine := values["affine"]
+		a, b, c, d, e, f = affine[0], affine[1], affine[2], affine[3], affine[4], affine[5]
+	} else if hasTo {
+		from := []float64{0, 0, 1, 0, 0, 1}
+		if hasFrom {
+			from = values["from"]
+		}
+		var err error
+		a, b, c, d, e, f, err = mapTriangle(from, values["to"])
+		if err != nil {
+			return fxn, err
+		}
+	} else {
+		sx, sy := 1.0, 1.0
+		if scale, ok := values["scale"]; ok {
+			sx, sy = scale[0], scale[len(scale)-1]
+		}
+		kx, ky := 0.0, 0.0
+		if shear, ok := values["shear"]; ok {
+			kx = shear[0]
+			if len(shear) > 1 {
+				ky = shear[1]
+			}
+		}
+		theta := 0.0
+		if rotate, ok := values["rotate"]; ok {
+			theta = rotate[0] * math.Pi / 180
+		}
+		if translate, ok := values["translate"]; ok {
+			e, f = translate[0], translate[1]
+		}
+		// rotation * shear * scale
+		sin, cos := math.Sincos(theta)
+		a = (cos - sin*ky) * sx
+		b = (cos*kx - sin) * sy
+		c = (sin + cos*ky) * sx
+		d = (sin*kx + cos) * sy
+	}
+	fxn[IFS_FXN_INDEX_A] = a
+	fxn[IFS_FXN_INDEX_B] = b
+	fxn[IFS_FXN_INDEX_C] = c
+	fxn[IFS_FXN_INDEX_D] = d
+	fxn[IFS_FXN_INDEX_E] = e
+	fxn[IFS_FXN_INDEX_F] = f
+	fxn[IFS_FXN_INDEX_PROBABILITY] = math.NaN()
+	if probability, ok := values["probability"]; ok {
+		fxn[IFS_FXN_INDEX_PROBABILITY] = probability[0]
+	}
+	return fxn, nil
+}
+
+// Computes the affine variables that map the triangle with the corners from
+// to the triangle with the corners to, where the corners are x,y pairs.
+func mapTriangle(from, to []float64) (a, b, c, d, e, f float64, err error) {
+	x1, y1, x2, y2, x3, y3 := from[0], from[1], from[2], from[3], from[4], from[5]
+	det := x1*(y2-y3) - y1*(x2-x3) + (x2*y3 - x3*y2)
+	if det == 0 {
+		err = errors.New("The points of from must not be on a line")
+		return
+	}
+	// solves [x y 1] * [a b e] = u for each corner using Cramer's rule
+	solve := func(u1, u2, u3 float64) (p, q, r float64) {
+		p = (u1*(y2-y3) - y1*(u2-u3) + (u2*y3 - u3*y2)) / det
+		q = (x1*(u2-u3) - u1*(x2-x3) + (x2*u3 - x3*u2)) / det
+		r = (x1*(y2*u3-y3*u2) - y1*(x2*u3-x3*u2) + u1*(x2*y3-x3*y2)) / det
+		return
+	}
+	a, b, e = solve(to[0], to[2], to[4])
+	c, d, f = solve(to[1], to[3], to[5])
+	return
+}
+
+// Checks that each set of the IFS is contractive, which is when the largest
+// singular value of its linear part is less than 1.
+func ValidateIFSContraction(functions [][IFS_FXN_VARIABLES_COUNT]float64) error {
+	for i, fxn := range functions {
+		a, b := fxn[IFS_FXN_INDEX_A], fxn[IFS_FXN_INDEX_B]
+		c, d := fxn[IFS_FXN_INDEX_C], fxn[IFS_FXN_INDEX_D]
+		p := (a*a + b*b + c*c + d*d) / 2
+		q := math.Sqrt(math.Max(0, p*p-(a*d-b*c)*(a*d-b*c)))
+		norm := math.Sqrt(p + q)
+		if norm >= 1 {
+			return fmt.Errorf("Transform %d is not contractive (scale factor %.4g)", i+1, norm)
+		}
+	}
+	return nil
+}
+
+// Checks that the probabilities of the sets of the IFS sum to 1. Missing
+// (NaN) probabilities are derived from the absolute determinants of the
+// sets, sharing what is left of the given probabilities. The probabilities
+// are then scaled to sum to exactly 1.
+func ValidateIFSVariables(functions [][IFS_FXN_VARIABLES_COUNT]float64) error {
+	givenSum, determinantSum := 0.0, 0.0
+	for i, fxn := range functions {
+		a, b := fxn[IFS_FXN_INDEX_A], fxn[IFS_FXN_INDEX_B]
+		c, d := fxn[IFS_FXN_INDEX_C], fxn[IFS_FXN_INDEX_D]
+		probability := fxn[IFS_FXN_INDEX_PROBABILITY]
+		if math.IsNaN(probability) {
+			determinantSum += math.Max(math.Abs(a*d-b*c), IFS_MIN_DETERMINANT)
+		} else if probability < 0 {
+			return fmt.Errorf("Transform %d has a negative probability", i+1)
+		} else {
+			givenSum += probability
+		}
+	}
+	remaining := 1 - givenSum
+	if determinantSum > 0 && remaining <= 0 {
+		return fmt.Errorf("The given probabilities sum to %.4g, which leaves nothing for the missing probabilities", givenSum)
+	}
+	for i, fxn := range functions {
+		if math.IsNaN(fxn[IFS_FXN_INDEX_PROBABILITY]) {
+			a, b := fxn[IFS_FXN_INDEX_A], fxn[IFS_FXN_INDEX_B]
+			c, d := fxn[IFS_FXN_INDEX_C], fxn[IFS_FXN_INDEX_D]
+			determinant := math.Max(math.Abs(a*d-b*c), IFS_MIN_DETERMINANT)
+			functions[i][IFS_FXN_INDEX_PROBABILITY] = remaining * determinant / determinantSum
+		}
+	}
+	sum := 0.0
+	for _, fxn := range functions {
+		sum += fxn[IFS_FXN_INDEX_PROBABILITY]
+	}
+	if math.Abs(sum-1) > IFS_PROBABILITY_TOLERANCE {
+		return fmt.Errorf("The probabilities sum to %.4g instead of 1", sum)
+	}
+	for i := range functions {
+		functions[i][IFS_FXN_INDEX_PROBABILITY] /= sum
+	}
+	return nil
+}
+
 // Retrieves a slice of colors for the IFS.
 func GetIFSColors(txt string, count int) []color.RGBA {
 	values, err := helpers.GetCSV(txt)
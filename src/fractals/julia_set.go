@@ -4,14 +4,14 @@ import (
 	"errors"
 	"image"
 	"image/color"
-	"image/png"
-	"io"
+	"image/draw"
 	"math"
 	"math/cmplx"
 	"strconv"
 	"strings"
 
 	"github.com/B3zaleel/fractage/src/helpers"
+	"github.com/llgcode/draw2d"
 )
 
 const (
@@ -25,6 +25,9 @@ const (
 	JULIA_SET_DEFAULT_VARIABLES_TEXT = "i=3+0i, k=0.0-0.01i"
 	JULIA_SET_DEFAULT_VARIABLE_I     = 3 + 0i
 	JULIA_SET_DEFAULT_VARIABLE_K     = 0.0 - 0.01i
+	// The default region of the parameter plane to display.
+	JULIA_SET_DEFAULT_PARAMETER_REGION = "-2.25, -1.5, 3, 3"
+	JULIA_SET_DEFAULT_MARKER_RADIUS    = 4
 )
 
 var (
@@ -58,6 +61,27 @@ var (
 		"abs_acosh4": func(props *JuliaSet) func(complex128) complex128 { return absTrig(props, cmplx.Acosh) },
 		"abs_atanh4": func(props *JuliaSet) func(complex128) complex128 { return absTrig(props, cmplx.Atanh) },
 	}
+
+	// The starting values of z for each series in the parameter plane, which
+	// are critical or asymptotic values of the series function.
+	JULIA_SET_CRITICAL_POINTS = map[string]func(*JuliaSet) complex128{
+		"classic":    func(props *JuliaSet) complex128 { return 0 },
+		"lace":       func(props *JuliaSet) complex128 { return 1 },
+		"phoenix":    func(props *JuliaSet) complex128 { return 0 },
+		"csin":       func(props *JuliaSet) complex128 { return math.Pi / 2 },
+		"ccos":       func(props *JuliaSet) complex128 { return 0 },
+		"ctan":       func(props *JuliaSet) complex128 { return 1i * props.C },
+		"abs_sin4":   func(props *JuliaSet) complex128 { return 0 },
+		"abs_cos4":   func(props *JuliaSet) complex128 { return math.Pi / 2 },
+		"abs_tan4":   func(props *JuliaSet) complex128 { return 0 },
+		"abs_cot4":   func(props *JuliaSet) complex128 { return math.Pi / 2 },
+		"abs_sinh4":  func(props *JuliaSet) complex128 { return 0 },
+		"abs_cosh4":  func(props *JuliaSet) complex128 { return 1i * math.Pi / 2 },
+		"abs_tanh4":  func(props *JuliaSet) complex128 { return 0 },
+		"abs_asinh4": func(props *JuliaSet) complex128 { return 0 },
+		"abs_acosh4": func(props *JuliaSet) complex128 { return 1 },
+		"abs_atanh4": func(props *JuliaSet) complex128 { return 0 },
+	}
 )
 
 // Properties of a Julia set image.
@@ -72,6 +96,15 @@ type JuliaSet struct {
 	Region             helpers.Rect
 	SeriesFunctionName string
 	Background         color.RGBA
+	BitDepth           int
+	ParameterSpace     bool
+	Z0                 complex128
+	UseCriticalPoint   bool
+	MarkC              bool
+	MarkerColor        color.RGBA
+	MarkerRadius       float64
+	Companion          bool
+	JuliaRegion        helpers.Rect
 	zPrev              complex128
 	zNext              complex128
 }
@@ -94,24 +127,60 @@ func cTrig(props *JuliaSet, trigFxn func(complex128) complex128) func(complex128
 	}
 }
 
-// Writes the Julia set image to the given output.
-func (props *JuliaSet) WriteImage(output io.Writer) error {
+// Creates the Julia set image.
+func (props *JuliaSet) CreateImage() (image.Image, error) {
 	viewport := image.Rect(0, 0, props.Width, props.Height)
-	img := image.NewRGBA(viewport)
+	img := helpers.NewImage(viewport, props.BitDepth)
 	helpers.FillImage(img, props.Background)
-	err := props.render(img)
-	if err != nil {
-		return err
+	var err error
+	if props.ParameterSpace && props.Companion {
+		err = props.renderCompanion(img)
+	} else {
+		err = props.render(img)
 	}
-	err = png.Encode(output, img)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	return img, nil
+}
+
+// Creates a copy of this Julia set that renders the given region with the
+// given number of iterations.
+func (props *JuliaSet) ZoomFrame(region helpers.Rect, iterations int) ZoomFractal {
+	frame := *props
+	frame.Region = region
+	frame.MaxIterations = iterations
+	frame.ColorPalette = props.ColorPalette.Copy()
+	return &frame
+}
+
+// Helper function for rendering the parameter plane beside the Julia set
+// of the value of c.
+func (props *JuliaSet) renderCompanion(img draw.RGBA64Image) error {
+	halfWidth := props.Width / 2
+	parameterSpace := *props
+	parameterSpace.Width = halfWidth
+	parameterSpace.MarkC = true
+	juliaSet := *props
+	juliaSet.Width = props.Width - halfWidth
+	juliaSet.ParameterSpace = false
+	juliaSet.MarkC = false
+	juliaSet.Region = props.JuliaRegion
+	for i, part := range []*JuliaSet{&parameterSpace, &juliaSet} {
+		partImg := helpers.NewImage(image.Rect(0, 0, part.Width, part.Height), props.BitDepth)
+		helpers.FillImage(partImg, part.Background)
+		err := part.render(partImg)
+		if err != nil {
+			return err
+		}
+		offset := image.Pt(i*halfWidth, 0)
+		draw.Draw(img, partImg.Bounds().Add(offset), partImg, image.Point{}, draw.Src)
 	}
 	return nil
 }
 
 // Helper function for rendering the Julia set.
-func (props *JuliaSet) render(img *image.RGBA) error {
+func (props *JuliaSet) render(img draw.RGBA64Image) error {
 	width, height := float64(props.Width), float64(props.Height)
 	step := math.Max(props.Region.Width/width, props.Region.Height/height)
 	xOffset := props.Region.X - (width*step-props.Region.Width)/2.0
@@ -120,13 +189,24 @@ func (props *JuliaSet) render(img *image.RGBA) error {
 	if err != nil {
 		return err
 	}
-	var pixelColor color.RGBA
+	var pixelColor color.RGBA64
 	var n int
+	c := props.C
+	defer func() { props.C = c }()
 	seriesFunction := JULIA_SET_SERIES[props.SeriesFunctionName](props)
+	criticalPoint := JULIA_SET_CRITICAL_POINTS[props.SeriesFunctionName]
 	for y := 0; y < int(height); y++ {
 		for x := 0; x < int(width); x++ {
 			n = 0
 			Z := complex(xOffset+float64(x)*step, yOffset+float64(y)*step)
+			if props.ParameterSpace {
+				props.C = Z
+				if props.UseCriticalPoint {
+					Z = criticalPoint(props)
+				} else {
+					Z = props.Z0
+				}
+			}
 			props.zPrev = Z
 			props.zNext = Z
 			seriesValue := math.Exp(-cmplx.Abs(Z))
@@ -148,9 +228,16 @@ func (props *JuliaSet) render(img *image.RGBA) error {
 					return err
 				}
 			}
-			img.Set(x, y, pixelColor)
+			img.SetRGBA64(x, y, pixelColor)
 		}
 	}
+	if props.ParameterSpace && props.MarkC {
+		markerX := (real(c) - xOffset) / step
+		markerY := (imag(c) - yOffset) / step
+		helpers.DrawOver(img, func(gc draw2d.GraphicContext) {
+			helpers.DrawFilledCircle(gc, markerX, markerY, props.MarkerRadius, props.MarkerColor, props.MarkerColor)
+		})
+	}
 	return nil
 }
 
@@ -0,0 +1,841 @@
+package fractals
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/B3zaleel/fractage/src/helpers"
+)
+
+const (
+	// The directory of the fractal files that are always available.
+	LIBRARY_DIRECTORY = "src/data/library"
+	// The extension of Fractint IFS files.
+	LIBRARY_EXTENSION_IFS = ".ifs"
+	// The extension of Fractint L-system files.
+	LIBRARY_EXTENSION_LSYSTEM = ".l"
+	// The extension of Apophysis and flam3 flame files.
+	LIBRARY_EXTENSION_FLAME = ".flame"
+	// The number of values in each set of a 3D Fractint IFS.
+	LIBRARY_IFS_3D_VARIABLES_COUNT = 13
+	// The symbols of a Fractint L-system that draw and move forward, besides F.
+	LIBRARY_LSYSTEM_DRAW_SYMBOLS = "D"
+	LIBRARY_LSYSTEM_SKIP_SYMBOLS = "GM"
+	// The number of entries that can be uploaded to the library.
+	LIBRARY_MAX_UPLOADED_ENTRIES = 1_000
+)
+
+var (
+	// The entries of the files in LIBRARY_DIRECTORY, which are parsed once.
+	libraryFiles      Library
+	libraryFilesError error
+	libraryFilesOnce  sync.Once
+	// The entries imported through uploads, which take precedence over the
+	// entries of the files in LIBRARY_DIRECTORY.
+	uploadedLibrary Library
+	// The entries of the files and the uploaded entries, which is replaced
+	// rather than modified when entries are uploaded and is nil until it's
+	// needed.
+	combinedLibrary *Library
+	libraryMutex    sync.Mutex
+)
+
+// Represents the fractals imported from Fractint and Apophysis files.
+type Library struct {
+	IFS      []IFSLibraryEntry
+	LSystems []LSystemLibraryEntry
+	Flames   []FlameLibraryEntry
+}
+
+// Represents an iterated function system of a Fractint .ifs file.
+type IFSLibraryEntry struct {
+	Name      string
+	Variables [][IFS_FXN_VARIABLES_COUNT]float64
+}
+
+// Represents a Lindenmayer system of a Fractint .l file.
+type LSystemLibraryEntry struct {
+	Name         string
+	Axiom        string
+	RewriteRules map[rune][]LindenmayerRule
+	TurningAngle float64
+	DrawSymbols  string
+	SkipSymbols  string
+}
+
+// Represents a fractal flame of an Apophysis or flam3 .flame file.
+type FlameLibraryEntry struct {
+	Name       string
+	Transforms []FlameTransform
+	Final      *FlameTransform
+	// The region given by the camera of the flame, which is empty when the
+	// flame has no camera.
+	Region     helpers.Rect
+	Gamma      float64
+	Vibrancy   float64
+	Background color.RGBA
+	// The palette of the flame, which has no transitions when the flame has
+	// no palette.
+	ColorPalette helpers.ColorPalette
+}
+
+// Loads the entries of the files in LIBRARY_DIRECTORY and the uploaded
+// entries. The entries are shared and mustn't be modified.
+func LoadLibrary() (Library, error) {
+	libraryFilesOnce.Do(func() {
+		libraryFiles, libraryFilesError = readLibraryFiles()
+	})
+	if libraryFilesError != nil {
+		return Library{}, libraryFilesError
+	}
+	libraryMutex.Lock()
+	defer libraryMutex.Unlock()
+	if combinedLibrary == nil {
+		var library Library
+		library.Add(libraryFiles)
+		library.Add(uploadedLibrary)
+		combinedLibrary = &library
+	}
+	return *combinedLibrary, nil
+}
+
+// Reads the entries of the files in LIBRARY_DIRECTORY.
+func readLibraryFiles() (Library, error) {
+	var library Library
+	files, err := os.ReadDir(LIBRARY_DIRECTORY)
+	if err != nil {
+		return library, err
+	}
+	for _, file := range files {
+		if file.IsDir() || !IsLibraryFile(file.Name()) {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(LIBRARY_DIRECTORY, file.Name()))
+		if err != nil {
+			return library, err
+		}
+		entries, err := ParseLibraryFile(file.Name(), content)
+		if err != nil {
+			return library, err
+		}
+		library.Add(entries)
+	}
+	return library, nil
+}
+
+// Adds entries to the uploaded entries of the library, unless they would
+// make the library hold more than LIBRARY_MAX_UPLOADED_ENTRIES of them.
+func AddToLibrary(entries Library) error {
+	libraryMutex.Lock()
+	defer libraryMutex.Unlock()
+	var uploaded Library
+	uploaded.Add(uploadedLibrary)
+	uploaded.Add(entries)
+	if uploaded.Count() > LIBRARY_MAX_UPLOADED_ENTRIES {
+		return fmt.Errorf("The library can't hold more than %d uploaded fractals", LIBRARY_MAX_UPLOADED_ENTRIES)
+	}
+	uploadedLibrary = uploaded
+	combinedLibrary = nil
+	return nil
+}
+
+// Checks if a file name has the extension of a supported fractal file.
+func IsLibraryFile(fileName string) bool {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case LIBRARY_EXTENSION_IFS, LIBRARY_EXTENSION_LSYSTEM, LIBRARY_EXTENSION_FLAME:
+		return true
+	}
+	return false
+}
+
+// Retrieves the entries of a fractal file, whose format is given by the
+// extension of its name.
+func ParseLibraryFile(fileName string, content []byte) (Library, error) {
+	var library Library
+	var err error
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case LIBRARY_EXTENSION_IFS:
+		library.IFS, err = ParseFractintIFS(content)
+	case LIBRARY_EXTENSION_LSYSTEM:
+		library.LSystems, err = ParseFractintLSystems(content)
+	case LIBRARY_EXTENSION_FLAME:
+		library.Flames, err = ParseFlameFile(content)
+	default:
+		err = fmt.Errorf("Unsupported file type %s", filepath.Ext(fileName))
+	}
+	if err != nil {
+		return library, fmt.Errorf("%s: %s", filepath.Base(fileName), err.Error())
+	}
+	return library, nil
+}
+
+// Adds the entries of another library, replacing the entries with the same
+// names.
+func (library *Library) Add(other Library) {
+	for _, entry := range other.IFS {
+		if i := library.indexOfIFS(entry.Name); i >= 0 {
+			library.IFS[i] = entry
+		} else {
+			library.IFS = append(library.IFS, entry)
+		}
+	}
+	for _, entry := range other.LSystems {
+		if i := library.indexOfLSystem(entry.Name); i >= 0 {
+			library.LSystems[i] = entry
+		} else {
+			library.LSystems = append(library.LSystems, entry)
+		}
+	}
+	for _, entry := range other.Flames {
+		if i := library.indexOfFlame(entry.Name); i >= 0 {
+			library.Flames[i] = entry
+		} else {
+			library.Flames = append(library.Flames, entry)
+		}
+	}
+}
+
+// Retrieves the number of entries of the library.
+func (library *Library) Count() int {
+	return len(library.IFS) + len(library.LSystems) + len(library.Flames)
+}
+
+// Retrieves the sorted names of the entries of each type of fractal.
+func (library *Library) Names() map[string][]string {
+	names := map[string][]string{
+		"flame":    make([]string, len(library.Flames)),
+		"ifs":      make([]string, len(library.IFS)),
+		"l-system": make([]string, len(library.LSystems)),
+	}
+	for i, entry := range library.Flames {
+		names["flame"][i] = entry.Name
+	}
+	for i, entry := range library.IFS {
+		names["ifs"][i] = entry.Name
+	}
+	for i, entry := range library.LSystems {
+		names["l-system"][i] = entry.Name
+	}
+	for _, values := range names {
+		sort.Slice(values, func(i, j int) bool {
+			return strings.ToLower(values[i]) < strings.ToLower(values[j])
+		})
+	}
+	return names
+}
+
+// Finds the IFS with the given name, ignoring case.
+func (library *Library) FindIFS(name string) (IFSLibraryEntry, error) {
+	i := library.indexOfIFS(strings.Trim(name, helpers.WHITESPACE_CUTSET))
+	if i < 0 {
+		return IFSLibraryEntry{}, fmt.Errorf("IFS not found in the library: %s", name)
+	}
+	entry := library.IFS[i]
+	entry.Variables = append([][IFS_FXN_VARIABLES_COUNT]float64(nil), entry.Variables...)
+	return entry, nil
+}
+
+// Finds the L-system with the given name, ignoring case.
+func (library *Library) FindLSystem(name string) (LSystemLibraryEntry, error) {
+	i := library.indexOfLSystem(strings.Trim(name, helpers.WHITESPACE_CUTSET))
+	if i < 0 {
+		return LSystemLibraryEntry{}, fmt.Errorf("L-system not found in the library: %s", name)
+	}
+	return library.LSystems[i], nil
+}
+
+// Finds the flame with the given name, ignoring case.
+func (library *Library) FindFlame(name string) (FlameLibraryEntry, error) {
+	i := library.indexOfFlame(strings.Trim(name, helpers.WHITESPACE_CUTSET))
+	if i < 0 {
+		return FlameLibraryEntry{}, fmt.Errorf("Flame not found in the library: %s", name)
+	}
+	entry := library.Flames[i]
+	entry.ColorPalette.Transitions = append([]helpers.Transition(nil), entry.ColorPalette.Transitions...)
+	return entry, nil
+}
+
+func (library *Library) indexOfIFS(name string) int {
+	for i, entry := range library.IFS {
+		if strings.EqualFold(entry.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (library *Library) indexOfLSystem(name string) int {
+	for i, entry := range library.LSystems {
+		if strings.EqualFold(entry.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (library *Library) indexOfFlame(name string) int {
+	for i, entry := range library.Flames {
+		if strings.EqualFold(entry.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Represents a whitespace-separated word of a Fractint file and the line
+// it is on.
+type fractintToken struct {
+	text string
+	line int
+}
+
+// Splits a Fractint file into words, leaving out ; comments and keeping
+// the braces of the entries as separate words.
+func tokenizeFractintFile(content []byte) []fractintToken {
+	var tokens []fractintToken
+	for i, line := range strings.Split(string(content), "\n") {
+		line, _, _ = strings.Cut(line, ";")
+		line = strings.ReplaceAll(line, "{", " { ")
+		line = strings.ReplaceAll(line, "}", " } ")
+		for _, word := range strings.Fields(line) {
+			tokens = append(tokens, fractintToken{text: word, line: i + 1})
+		}
+	}
+	return tokens
+}
+
+// Retrieves the iterated function systems of a Fractint .ifs file, where
+// each entry is a name followed by rows of the variables a, b, c, d, e, f
+// and p in braces. 3D entries are skipped.
+func ParseFractintIFS(content []byte) ([]IFSLibraryEntry, error) {
+	var entries []IFSLibraryEntry
+	tokens := tokenizeFractintFile(content)
+	for i := 0; i < len(tokens); {
+		start := tokens[i]
+		if start.text == "{" || start.text == "}" {
+			return nil, fmt.Errorf("line %d: Expected the name of an entry before %s", start.line, start.text)
+		}
+		is3D := false
+		i++
+		for ; i < len(tokens) && tokens[i].text != "{"; i++ {
+			if tokens[i].text == "}" {
+				return nil, fmt.Errorf("line %d: Expected { after %s", tokens[i].line, start.text)
+			}
+			if strings.EqualFold(tokens[i].text, "(3D)") {
+				is3D = true
+			}
+		}
+		if i >= len(tokens) {
+			return nil, fmt.Errorf("line %d: Expected { after %s", start.line, start.text)
+		}
+		var values []float64
+		for i++; i < len(tokens) && tokens[i].text != "}"; i++ {
+			value, err := strconv.ParseFloat(tokens[i].text, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: Invalid number %s in %s", tokens[i].line, tokens[i].text, start.text)
+			}
+			values = append(values, value)
+		}
+		if i >= len(tokens) {
+			return nil, fmt.Errorf("line %d: %s is missing its closing }", start.line, start.text)
+		}
+		end := tokens[i]
+		i++
+		count := IFS_FXN_VARIABLES_COUNT
+		if is3D {
+			count = LIBRARY_IFS_3D_VARIABLES_COUNT
+		}
+		if len(values) == 0 || len(values)%count != 0 {
+			return nil, fmt.Errorf("line %d: %s has %d values, which isn't a multiple of %d", end.line, start.text, len(values), count)
+		}
+		if is3D {
+			continue
+		}
+		variables := make([][IFS_FXN_VARIABLES_COUNT]float64, len(values)/count)
+		for j := range variables {
+			copy(variables[j][:], values[j*count:(j+1)*count])
+		}
+		err := ValidateIFSVariables(variables)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s: %s", start.line, start.text, err.Error())
+		}
+		entries = append(entries, IFSLibraryEntry{Name: start.text, Variables: variables})
+	}
+	return entries, nil
+}
+
+// Retrieves the Lindenmayer systems of a Fractint .l file, where each entry
+// is a name followed by an Angle, an Axiom and rewrite rules in braces, one
+// per line.
+func ParseFractintLSystems(content []byte) ([]LSystemLibraryEntry, error) {
+	var entries []LSystemLibraryEntry
+	var entry *LSystemLibraryEntry
+	entryLine := 0
+	for i, line := range strings.Split(string(content), "\n") {
+		lineNumber := i + 1
+		line, _, _ = strings.Cut(line, ";")
+		if entry == nil {
+			line = strings.Trim(line, helpers.WHITESPACE_CUTSET)
+			if line == "" {
+				continue
+			}
+			before, after, found := strings.Cut(line, "{")
+			name := strings.Fields(before)
+			if !found || len(name) == 0 {
+				return nil, fmt.Errorf("line %d: Expected the name of an entry followed by {", lineNumber)
+			}
+			entry = &LSystemLibraryEntry{
+				Name:         name[0],
+				RewriteRules: map[rune][]LindenmayerRule{},
+				DrawSymbols:  LIBRARY_LSYSTEM_DRAW_SYMBOLS,
+				SkipSymbols:  LIBRARY_LSYSTEM_SKIP_SYMBOLS,
+			}
+			entryLine = lineNumber
+			line = after
+		}
+		line, _, closed := strings.Cut(line, "}")
+		line = strings.Trim(line, helpers.WHITESPACE_CUTSET)
+		if line != "" {
+			err := parseFractintLSystemLine(entry, line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s: %s", lineNumber, entry.Name, err.Error())
+			}
+		}
+		if closed {
+			if entry.TurningAngle == 0 {
+				return nil, fmt.Errorf("line %d: %s has no angle", entryLine, entry.Name)
+			}
+			if entry.Axiom == "" {
+				return nil, fmt.Errorf("line %d: %s has no axiom", entryLine, entry.Name)
+			}
+			entries = append(entries, *entry)
+			entry = nil
+		}
+	}
+	if entry != nil {
+		return nil, fmt.Errorf("line %d: %s is missing its closing }", entryLine, entry.Name)
+	}
+	return entries, nil
+}
+
+// Adds an Angle, an Axiom or a rewrite rule to an entry of a Fractint .l
+// file.
+func parseFractintLSystemLine(entry *LSystemLibraryEntry, line string) error {
+	fields := strings.Fields(line)
+	keyword := strings.ToLower(fields[0])
+	if keyword == "angle" {
+		if len(fields) != 2 {
+			return errors.New("Angle must have one value")
+		}
+		divisions, err := strconv.Atoi(fields[1])
+		if err != nil || divisions <= 0 {
+			return fmt.Errorf("Invalid angle %s", fields[1])
+		}
+		entry.TurningAngle = 360.0 / float64(divisions)
+		return nil
+	}
+	if keyword == "axiom" {
+		axiom, err := translateFractintCommands(strings.Join(fields[1:], ""))
+		if err != nil {
+			return err
+		}
+		if axiom == "" {
+			return errors.New("The axiom is empty")
+		}
+		entry.Axiom = axiom
+		return nil
+	}
+	before, after, found := strings.Cut(strings.Join(fields, ""), "=")
+	if !found {
+		return fmt.Errorf("Invalid line %s", line)
+	}
+	variable := []rune(strings.ToUpper(before))
+	if len(variable) != 1 {
+		return errors.New("The variable must be a single character")
+	}
+	if _, ok := entry.RewriteRules[variable[0]]; ok {
+		return fmt.Errorf("%c has more than one rule", variable[0])
+	}
+	replacement, err := translateFractintCommands(after)
+	if err != nil {
+		return err
+	}
+	rule, err := newLindenmayerRule(variable[0], replacement)
+	if err != nil {
+		return err
+	}
+	entry.RewriteRules[variable[0]] = []LindenmayerRule{rule}
+	return nil
+}
+
+// Converts the commands of a Fractint L-system to the symbols of a
+// LindenmayerSystem. Fractint symbols aren't case sensitive, ! reverses the
+// turning directions and the color commands C, < and > are left out.
+func translateFractintCommands(txt string) (string, error) {
+	var result strings.Builder
+	commands := []rune(strings.ToUpper(txt))
+	for i := 0; i < len(commands); i++ {
+		switch c := commands[i]; c {
+		case '!':
+			result.WriteRune('&')
+		case 'C', '<', '>':
+			for i+1 < len(commands) && commands[i+1] >= '0' && commands[i+1] <= '9' {
+				i++
+			}
+		case '\\', '/', '@':
+			return "", fmt.Errorf("The command %c isn't supported", c)
+		default:
+			result.WriteRune(c)
+		}
+	}
+	return result.String(), nil
+}
+
+// Retrieves the fractal flames of an Apophysis or flam3 .flame file.
+func ParseFlameFile(content []byte) ([]FlameLibraryEntry, error) {
+	var entries []FlameLibraryEntry
+	var entry *FlameLibraryEntry
+	var palette *strings.Builder
+	var colors map[int]string
+	entryLine := 0
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	lineOf := func(offset int64) int {
+		return 1 + bytes.Count(content[:offset], []byte("\n"))
+	}
+	for {
+		line := lineOf(decoder.InputOffset())
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var syntaxErr *xml.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				return nil, fmt.Errorf("line %d: %s", syntaxErr.Line, syntaxErr.Msg)
+			}
+			return nil, fmt.Errorf("line %d: %s", line, err.Error())
+		}
+		switch element := token.(type) {
+		case xml.StartElement:
+			attributes := map[string]string{}
+			for _, attribute := range element.Attr {
+				attributes[attribute.Name.Local] = attribute.Value
+			}
+			switch element.Name.Local {
+			case "flame":
+				if entry != nil {
+					return nil, fmt.Errorf("line %d: Flames can't be nested", line)
+				}
+				entry, err = newFlameLibraryEntry(attributes, len(entries)+1)
+				colors = map[int]string{}
+				entryLine = line
+			case "xform", "finalxform":
+				if entry == nil {
+					return nil, fmt.Errorf("line %d: %s must be inside a flame", line, element.Name.Local)
+				}
+				var transform FlameTransform
+				transform, err = parseFlameXForm(attributes)
+				if element.Name.Local == "xform" {
+					entry.Transforms = append(entry.Transforms, transform)
+				} else if entry.Final != nil {
+					err = errors.New("A flame can only have one final transform")
+				} else {
+					entry.Final = &transform
+				}
+			case "palette":
+				if entry == nil {
+					return nil, fmt.Errorf("line %d: palette must be inside a flame", line)
+				}
+				if format, ok := attributes["format"]; ok && !strings.EqualFold(format, "RGB") {
+					err = fmt.Errorf("Unsupported palette format %s", format)
+				}
+				palette = &strings.Builder{}
+			case "color":
+				if entry == nil {
+					return nil, fmt.Errorf("line %d: color must be inside a flame", line)
+				}
+				var index int
+				var hexColor string
+				index, hexColor, err = parseFlameColor(attributes)
+				colors[index] = hexColor
+			}
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s", line, err.Error())
+			}
+		case xml.CharData:
+			if palette != nil {
+				palette.Write(element)
+			}
+		case xml.EndElement:
+			switch element.Name.Local {
+			case "palette":
+				if palette == nil {
+					break
+				}
+				values := strings.Join(strings.Fields(palette.String()), "")
+				if len(values)%6 != 0 {
+					return nil, fmt.Errorf("line %d: The palette must have 6 hexadecimal digits per color", line)
+				}
+				for i := 0; i < len(values); i += 6 {
+					if _, err := strconv.ParseUint(values[i:i+6], 16, 32); err != nil {
+						return nil, fmt.Errorf("line %d: Invalid palette color %s", line, values[i:i+6])
+					}
+					colors[i/6] = "#" + strings.ToLower(values[i:i+6])
+				}
+				palette = nil
+			case "flame":
+				if entry == nil {
+					break
+				}
+				if len(entry.Transforms) == 0 {
+					return nil, fmt.Errorf("line %d: %s has no transforms", entryLine, entry.Name)
+				}
+				entry.finish(colors)
+				entries = append(entries, *entry)
+				entry = nil
+			}
+		}
+	}
+	if entry != nil {
+		return nil, fmt.Errorf("line %d: %s is missing its closing tag", entryLine, entry.Name)
+	}
+	if len(entries) == 0 {
+		return nil, errors.New("line 1: The file has no flames")
+	}
+	return entries, nil
+}
+
+// Creates a flame entry from the attributes of a flame element, where
+// number is the position of the flame in its file.
+func newFlameLibraryEntry(attributes map[string]string, number int) (*FlameLibraryEntry, error) {
+	entry := &FlameLibraryEntry{
+		Name:       attributes["name"],
+		Gamma:      FLAME_DEFAULT_GAMMA,
+		Vibrancy:   FLAME_DEFAULT_VIBRANCY,
+		Background: color.RGBA{0, 0, 0, 255},
+	}
+	if entry.Name == "" {
+		entry.Name = fmt.Sprintf("flame_%d", number)
+	}
+	numbers := map[string][]float64{}
+	for _, name := range []string{"size", "center", "scale", "zoom", "gamma", "vibrancy", "background"} {
+		value, ok := attributes[name]
+		if !ok {
+			continue
+		}
+		for _, field := range strings.Fields(value) {
+			number, err := strconv.ParseFloat(field, 64)
+			if err != nil {
+				return entry, fmt.Errorf("Invalid %s %s", name, value)
+			}
+			numbers[name] = append(numbers[name], number)
+		}
+	}
+	if gamma := numbers["gamma"]; len(gamma) == 1 && gamma[0] > 0 {
+		entry.Gamma = gamma[0]
+	}
+	if vibrancy := numbers["vibrancy"]; len(vibrancy) == 1 {
+		entry.Vibrancy = math.Max(0, math.Min(1, vibrancy[0]))
+	}
+	if background := numbers["background"]; len(background) == 3 {
+		// flam3 uses values between 0 and 1, older files use 0 to 255
+		factor := 255.0
+		if background[0] > 1 || background[1] > 1 || background[2] > 1 {
+			factor = 1
+		}
+		channels := [3]uint8{}
+		for i := range channels {
+			channels[i] = uint8(math.Max(0, math.Min(255, background[i]*factor)))
+		}
+		entry.Background = color.RGBA{channels[0], channels[1], channels[2], 255}
+	}
+	size, center, scale := numbers["size"], numbers["center"], numbers["scale"]
+	if len(size) == 2 && len(scale) == 1 && scale[0] > 0 {
+		pixelsPerUnit := scale[0]
+		if zoom := numbers["zoom"]; len(zoom) == 1 {
+			pixelsPerUnit *= math.Pow(2, zoom[0])
+		}
+		x, y := 0.0, 0.0
+		if len(center) == 2 {
+			x, y = center[0], center[1]
+		}
+		width, height := size[0]/pixelsPerUnit, size[1]/pixelsPerUnit
+		// the y-axis of flame files points down
+		entry.Region = helpers.Rect{X: x - width/2, Y: -y - height/2, Width: width, Height: height}
+	}
+	return entry, nil
+}
+
+// Retrieves a flame transform from the attributes of an xform or
+// finalxform element.
+func parseFlameXForm(attributes map[string]string) (FlameTransform, error) {
+	transform := FlameTransform{
+		Affine:     [6]float64{1, 0, 0, 1, 0, 0},
+		Weight:     FLAME_DEFAULT_TRANSFORM_WEIGHT,
+		Color:      math.NaN(),
+		ColorSpeed: FLAME_DEFAULT_TRANSFORM_COLOR_SPEED,
+	}
+	names := make([]string, 0, len(attributes))
+	for name := range attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := attributes[name]
+		switch name {
+		case "coefs", "post":
+			var coefficients [6]float64
+			fields := strings.Fields(value)
+			if len(fields) != len(coefficients) {
+				return transform, fmt.Errorf("%s must have %d values", name, len(coefficients))
+			}
+			for i, field := range fields {
+				number, err := strconv.ParseFloat(field, 64)
+				if err != nil {
+					return transform, fmt.Errorf("Invalid %s %s", name, value)
+				}
+				coefficients[i] = number
+			}
+			// the coefficients are stored by column
+			coefficients = [6]float64{coefficients[0], coefficients[2], coefficients[1], coefficients[3], coefficients[4], coefficients[5]}
+			if name == "coefs" {
+				transform.Affine = coefficients
+			} else if coefficients != [6]float64{1, 0, 0, 1, 0, 0} {
+				transform.Post = coefficients
+				transform.HasPost = true
+			}
+		case "weight", "color", "symmetry", "color_speed":
+			// old flame files give two colors, of which the first is used
+			fields := strings.Fields(value)
+			if len(fields) == 0 {
+				return transform, fmt.Errorf("Invalid %s %s", name, value)
+			}
+			number, err := strconv.ParseFloat(fields[0], 64)
+			if err != nil {
+				return transform, fmt.Errorf("Invalid %s %s", name, value)
+			}
+			switch name {
+			case "weight":
+				if number < 0 {
+					return transform, errors.New("weight must be at least 0")
+				}
+				transform.Weight = number
+			case "color":
+				transform.Color = math.Max(0, math.Min(1, number))
+			case "symmetry":
+				if _, ok := attributes["color_speed"]; !ok {
+					transform.ColorSpeed = math.Max(0, math.Min(1, (1-number)/2))
+				}
+			case "color_speed":
+				transform.ColorSpeed = math.Max(0, math.Min(1, number))
+			}
+		default:
+			variation := strings.TrimSuffix(name, "3D")
+			fxn, ok := FLAME_VARIATIONS[variation]
+			weight, err := strconv.ParseFloat(value, 64)
+			if !ok {
+				// variation parameters and other attributes are left out
+				if err == nil && weight != 0 && !strings.Contains(name, "_") {
+					return transform, fmt.Errorf("Unsupported variation %s", name)
+				}
+				continue
+			}
+			if err != nil {
+				return transform, fmt.Errorf("Invalid weight of %s: %s", name, value)
+			}
+			if weight != 0 {
+				transform.Variations = append(transform.Variations, FlameVariation{Name: variation, Weight: weight, Fxn: fxn})
+			}
+		}
+	}
+	if len(transform.Variations) == 0 {
+		transform.Variations = []FlameVariation{{Name: "linear", Weight: 1, Fxn: linear_variation}}
+	}
+	return transform, nil
+}
+
+// Retrieves the index and the hexadecimal value of a color element of a
+// flame palette.
+func parseFlameColor(attributes map[string]string) (int, string, error) {
+	index, err := strconv.Atoi(attributes["index"])
+	if err != nil || index < 0 || index >= FLAME_PALETTE_SIZE {
+		return 0, "", fmt.Errorf("Invalid color index %s", attributes["index"])
+	}
+	fields := strings.Fields(attributes["rgb"])
+	if len(fields) != 3 {
+		return 0, "", fmt.Errorf("Invalid color %s", attributes["rgb"])
+	}
+	var channels [3]uint8
+	for i, field := range fields {
+		number, err := strconv.ParseFloat(field, 64)
+		if err != nil || number < 0 || number > 255 {
+			return 0, "", fmt.Errorf("Invalid color %s", attributes["rgb"])
+		}
+		channels[i] = uint8(number)
+	}
+	return index, fmt.Sprintf("#%02x%02x%02x", channels[0], channels[1], channels[2]), nil
+}
+
+// Completes a flame entry with the colors of its palette, the default
+// colors of its transforms and a flip of the y-axis.
+func (entry *FlameLibraryEntry) finish(colors map[int]string) {
+	if len(colors) > 0 {
+		indices := make([]int, 0, len(colors))
+		last := 0
+		for index := range colors {
+			indices = append(indices, index)
+			if index > last {
+				last = index
+			}
+		}
+		sort.Ints(indices)
+		entry.ColorPalette = helpers.ColorPalette{Name: entry.Name}
+		for _, index := range indices {
+			position := 0.0
+			if last > 0 {
+				position = float64(index) / float64(last)
+			}
+			entry.ColorPalette.Transitions = append(entry.ColorPalette.Transitions, helpers.Transition{
+				Color:    colors[index],
+				Position: float32(position),
+			})
+		}
+	}
+	for i := range entry.Transforms {
+		if math.IsNaN(entry.Transforms[i].Color) {
+			entry.Transforms[i].Color = 0
+			if len(entry.Transforms) > 1 {
+				entry.Transforms[i].Color = float64(i) / float64(len(entry.Transforms)-1)
+			}
+		}
+	}
+	// the y-axis of flame files points down, so the plotted points are
+	// flipped by the post transform of the final transform
+	if entry.Final == nil {
+		entry.Final = &FlameTransform{
+			Affine:     [6]float64{1, 0, 0, 1, 0, 0},
+			Variations: []FlameVariation{{Name: "linear", Weight: 1, Fxn: linear_variation}},
+		}
+	} else if math.IsNaN(entry.Final.Color) {
+		entry.Final.Color = 0
+		entry.Final.ColorSpeed = 0
+	}
+	if !entry.Final.HasPost {
+		entry.Final.Post = [6]float64{1, 0, 0, 1, 0, 0}
+		entry.Final.HasPost = true
+	}
+	for _, i := range []int{2, 3, 5} {
+		entry.Final.Post[i] = -entry.Final.Post[i]
+	}
+}
@@ -5,27 +5,79 @@ import (
 	"fmt"
 	"image"
 	"image/color"
-	"image/png"
 	"io"
 	"math"
+	"math/rand"
+	"strconv"
 	"strings"
+	"unicode"
 
 	"github.com/B3zaleel/fractage/src/helpers"
+	math_helpers "github.com/B3zaleel/fractage/src/helpers/math"
+	"github.com/llgcode/draw2d"
 	"github.com/llgcode/draw2d/draw2dimg"
+	"github.com/llgcode/draw2d/draw2dkit"
+)
+
+const (
+	// The largest difference between 1 and the sum of the probabilities of
+	// the rewrite rules of a variable.
+	LSYSTEM_PROBABILITY_TOLERANCE = 0.01
+	LSYSTEM_FIT_CONTAIN           = "contain"
+	LSYSTEM_FIT_COVER             = "cover"
+	LSYSTEM_COLORING_SOLID        = "solid"
+	LSYSTEM_COLORING_INDEX        = "index"
+	LSYSTEM_COLORING_DEPTH        = "depth"
+	LSYSTEM_COLORING_LENGTH       = "length"
 )
 
 type LindenmayerSystem struct {
-	Width                 int
-	Height                int
-	Axiom                 string
-	Iterations            int
-	RewriteRules          map[rune]string
-	DrawSymbols           string
-	SkipSymbols           string
-	Angle                 float64
-	Color                 color.RGBA
-	UseRandomColors       bool
-	Focus                 bool
+	Width        int
+	Height       int
+	Axiom        string
+	Iterations   int
+	RewriteRules map[rune][]LindenmayerRule
+	// The seed of the random choices of the stochastic rewrite rules.
+	Seed int64
+	// The symbols that are skipped when matching the contexts of the rules.
+	IgnoreSymbols string
+	// The largest number of symbols the derivation can have, or 0 for no
+	// limit.
+	MaxSymbols      int64
+	DrawSymbols     string
+	SkipSymbols     string
+	Angle           float64
+	Color           color.RGBA
+	UseRandomColors bool
+	// How the lines are colored from the color palette, unless it's solid.
+	Coloring     string
+	ColorPalette helpers.ColorPalette
+	// The number of color indices that ' and ; step through, which are
+	// spread evenly over the color palette.
+	ColorCount int
+	// The color polygons are filled with instead of the color of their
+	// lines.
+	FillColor    color.RGBA
+	UseFillColor bool
+	// The diameter of the dots drawn by @.
+	DotDiameter float64
+	// Whether the turtle moves in the plane (2d) or in space (3d). The
+	// drawing in space is always fitted to the image.
+	Mode       string
+	Projection string
+	// The angles in degrees of the camera around the vertical axis and above
+	// the horizontal plane.
+	Azimuth   float64
+	Elevation float64
+	// The distance of the perspective camera from the center of the drawing
+	// relative to the radius of the drawing.
+	CameraDistance float64
+	Focus          bool
+	// The space left around the system when it's fitted to the image.
+	Margin float64
+	// Whether the fitted system is wholly in the image (contain) or fills it
+	// (cover).
+	Fit                   string
 	TurningAngle          float64
 	Position              string
 	LineWidth             float64
@@ -36,6 +88,45 @@ type LindenmayerSystem struct {
 	Background            color.RGBA
 }
 
+// Represents a module of a generator, which is a symbol with the values of
+// its parameters.
+type LindenmayerModule struct {
+	Symbol     rune
+	Parameters []float64
+}
+
+// Represents a module in the left side of a rewrite rule, which is a symbol
+// with the names of its parameters.
+type LindenmayerPattern struct {
+	Symbol     rune
+	Parameters []string
+}
+
+// Represents a module in the right side of a rewrite rule, whose parameters
+// are expressions of the parameters of the left side.
+type LindenmayerProduction struct {
+	Symbol     rune
+	Parameters []math_helpers.RealExpression
+}
+
+// Represents a right side of a rewrite rule, which is chosen with its
+// probability from the right sides of the rule.
+type LindenmayerSuccessor struct {
+	Modules     []LindenmayerProduction
+	Probability float64
+}
+
+// Represents a rewrite rule of a module, which applies when the module has
+// as many parameters as the predecessor, lies between the contexts and
+// meets the condition.
+type LindenmayerRule struct {
+	Predecessor  LindenmayerPattern
+	LeftContext  []LindenmayerPattern
+	RightContext []LindenmayerPattern
+	Condition    *math_helpers.RealExpression
+	Successors   []LindenmayerSuccessor
+}
+
 // Represents a drawing state
 type State struct {
 	Angle              float64
@@ -45,255 +136,1104 @@ type State struct {
 	SwapTurnDirections bool
 	X                  float64
 	Y                  float64
+	ColorIndex         int
+	PathLength         float64
 }
 
-// Writes the Lindenmayer system image to the given output.
-func (props *LindenmayerSystem) WriteImage(output io.Writer) error {
-	var x, y float64
-	generator := props.BuildGenerator()
-	x, y, _ = ParseLSystemPosition(props.Position, float64(props.Width), float64(props.Height))
+// Creates the Lindenmayer system image.
+func (props *LindenmayerSystem) CreateImage() (image.Image, error) {
 	viewport := image.Rect(0, 0, props.Width, props.Height)
 	img := image.NewRGBA(viewport)
 	gc := draw2dimg.NewGraphicContext(img)
 	helpers.FillImage(img, props.Background)
-	props.render(gc, &generator, x, y)
-	err := png.Encode(output, img)
+	err := props.draw(gc)
+	if err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+// Writes the Lindenmayer system as a PDF document to the given output, whose
+// page has the size of the image at the resolution of the options.
+func (props *LindenmayerSystem) WritePDF(output io.Writer, options helpers.PdfOptions) error {
+	pdf, gc := helpers.NewPdfGraphicContext(props.Width, props.Height, props.Background, options)
+	err := props.draw(gc)
 	if err != nil {
 		return err
 	}
-	return nil
+	return helpers.WritePdf(output, pdf)
+}
+
+// Writes the Lindenmayer system as an SVG document to the given output.
+func (props *LindenmayerSystem) WriteSVG(output io.Writer) error {
+	svg, gc := helpers.NewSvgGraphicContext(props.Width, props.Height, props.Background)
+	err := props.draw(gc)
+	if err != nil {
+		return err
+	}
+	return helpers.WriteSvg(output, svg)
 }
 
-func (props *LindenmayerSystem) render(gc *draw2dimg.GraphicContext, generator *[]rune, startX, startY float64) {
+// Draws the Lindenmayer system with the turtle of its mode.
+func (props *LindenmayerSystem) draw(gc draw2d.GraphicContext) error {
+	var x, y float64
+	x, y, _ = ParseLSystemPosition(props.Position, float64(props.Width), float64(props.Height))
+	if props.Coloring != LSYSTEM_COLORING_SOLID {
+		err := props.ColorPalette.TranslateColorTransitions()
+		if err != nil {
+			return err
+		}
+	}
+	if props.Mode == LSYSTEM_MODE_3D {
+		return props.renderSpace(gc)
+	}
+	view := lindenmayerView{Scale: 1}
+	var bounds lindenmayerBounds
+	if props.Focus || props.Coloring == LSYSTEM_COLORING_DEPTH || props.Coloring == LSYSTEM_COLORING_LENGTH {
+		var err error
+		bounds, err = props.render(nil, x, y, view, bounds)
+		if err != nil {
+			return err
+		}
+	}
+	if props.Focus && !bounds.Empty {
+		view = props.fitView(bounds)
+	}
+	_, err := props.render(gc, x, y, view, bounds)
+	return err
+}
+
+// Represents the bounds of the drawing of a Lindenmayer system, which are
+// the bounds of the points of the turtle that are drawn, the largest half
+// width of the lines and dots, and the largest branch depth and path length.
+type lindenmayerBounds struct {
+	Empty         bool
+	MinX          float64
+	MinY          float64
+	MaxX          float64
+	MaxY          float64
+	HalfWidth     float64
+	MaxDepth      int
+	MaxPathLength float64
+}
+
+// Adds a point of the turtle that's drawn with the given line width.
+func (bounds *lindenmayerBounds) add(x, y, lineWidth float64) {
+	if bounds.Empty {
+		bounds.MinX, bounds.MaxX, bounds.MinY, bounds.MaxY = x, x, y, y
+		bounds.Empty = false
+	}
+	bounds.MinX = math.Min(bounds.MinX, x)
+	bounds.MaxX = math.Max(bounds.MaxX, x)
+	bounds.MinY = math.Min(bounds.MinY, y)
+	bounds.MaxY = math.Max(bounds.MaxY, y)
+	bounds.HalfWidth = math.Max(bounds.HalfWidth, lineWidth/2)
+}
+
+// Represents the scaling and translation of the points of the turtle to the
+// image. The line widths aren't scaled.
+type lindenmayerView struct {
+	Scale   float64
+	OffsetX float64
+	OffsetY float64
+}
+
+func (view *lindenmayerView) apply(x, y float64) (float64, float64) {
+	return x*view.Scale + view.OffsetX, y*view.Scale + view.OffsetY
+}
+
+// Centers the drawing with the given bounds in the image and scales it to fit
+// in the image without its margin, either whole or covering the image.
+func (props *LindenmayerSystem) fitView(bounds lindenmayerBounds) lindenmayerView {
+	width, height := bounds.MaxX-bounds.MinX, bounds.MaxY-bounds.MinY
+	availableWidth := float64(props.Width) - 2*props.Margin - 2*bounds.HalfWidth
+	availableHeight := float64(props.Height) - 2*props.Margin - 2*bounds.HalfWidth
+	scale := 1.0
+	if width > 0 && height > 0 {
+		scaleX, scaleY := availableWidth/width, availableHeight/height
+		if props.Fit == LSYSTEM_FIT_COVER {
+			scale = math.Max(scaleX, scaleY)
+		} else {
+			scale = math.Min(scaleX, scaleY)
+		}
+	} else if width > 0 {
+		scale = availableWidth / width
+	} else if height > 0 {
+		scale = availableHeight / height
+	}
+	if scale <= 0 {
+		scale = 1
+	}
+	return lindenmayerView{
+		Scale:   scale,
+		OffsetX: float64(props.Width)/2 - scale*(bounds.MinX+bounds.MaxX)/2,
+		OffsetY: float64(props.Height)/2 - scale*(bounds.MinY+bounds.MaxY)/2,
+	}
+}
+
+// Draws the derivation of this Lindenmayer system with the turtle starting
+// at the given point, and retrieves the bounds of the drawing before the
+// view is applied. It only measures the bounds without a graphic context.
+// The measured bounds scale the depth and length colorings.
+func (props *LindenmayerSystem) render(gc draw2d.GraphicContext, startX, startY float64, view lindenmayerView, measured lindenmayerBounds) (lindenmayerBounds, error) {
 	drawingStates := make([]State, 1)
-	color := props.Color
+	solidColor := props.Color
 	if props.UseRandomColors && gc != nil {
-		color = helpers.RandomColor()
+		solidColor = helpers.RandomColor()
 	}
 	n := len(drawingStates)
-	bounds := helpers.Rect{
-		X:      startX,
-		Y:      startY,
-		Width:  startX,
-		Height: startY,
-	}
-	for round := 1; round < 3; round++ {
-		i := 0
-		polygonOpen := false
-		if props.Focus && round == 2 {
-			// adjust the drawing parameters
-			scaleX := float64(props.Width) / bounds.Width
-			scaleY := float64(props.Height) / bounds.Height
-			step := math.Min(float64(scaleX), float64(scaleY))
-			props.LineLength = step
-		}
-		drawingStates[0] = State{
-			Angle:              props.Angle,
-			LineWidth:          props.LineWidth,
-			LineLength:         props.LineLength,
-			TurningAngle:       props.TurningAngle,
-			SwapTurnDirections: false,
-			X:                  startX,
-			Y:                  startY,
-		}
-		for _, c := range *generator {
-			switch c {
-			case 'F', 'f':
-				{
-					x0, y0 := drawingStates[i].X, drawingStates[i].Y
-					x1 := x0 + drawingStates[i].LineLength*math.Cos(drawingStates[i].Angle*math.Pi/180)
-					y1 := y0 + drawingStates[i].LineLength*math.Sin(drawingStates[i].Angle*math.Pi/180)
-					if !props.Focus || (props.Focus && round == 2) {
-						if !polygonOpen {
-							gc.SetLineWidth(drawingStates[i].LineWidth)
-							gc.SetStrokeColor(color)
-							gc.BeginPath()
-						}
-						canDraw := true
-						if canDraw {
-							gc.MoveTo(x0, y0)
-							if c == 'F' {
-								gc.LineTo(x1, y1)
-							} else {
-								gc.MoveTo(x1, y1)
-							}
-						}
-						if !polygonOpen {
-							gc.Close()
-							gc.Stroke()
-						}
-					}
-					if x1 > bounds.Width {
-						bounds.Width = x1
-					}
-					if x1 < bounds.X {
-						bounds.X = x1
-					}
-					if y1 > bounds.Height {
-						bounds.Height = y1
-					}
-					if y1 < bounds.Y {
-						bounds.Y = y1
-					}
-					drawingStates[i].X = x1
-					drawingStates[i].Y = y1
+	bounds := lindenmayerBounds{Empty: true}
+	i := 0
+	polygonOpen, polygonStarted := false, false
+	// the color of the lines of the current state
+	strokeColor := func() color.RGBA {
+		return props.getColor(solidColor, drawingStates[i].ColorIndex, i, drawingStates[i].PathLength, measured)
+	}
+	drawingStates[0] = State{
+		Angle:              props.Angle,
+		LineWidth:          props.LineWidth,
+		LineLength:         props.LineLength,
+		TurningAngle:       props.TurningAngle,
+		SwapTurnDirections: false,
+		X:                  startX,
+		Y:                  startY,
+	}
+	err := props.Expand(func(module LindenmayerModule) {
+		c, parameters := module.Symbol, module.Parameters
+		switch c {
+		case 'F', 'f':
+			{
+				length := drawingStates[i].LineLength
+				if len(parameters) > 0 {
+					length = parameters[0]
 				}
-			case '+':
-				{
-					if drawingStates[i].SwapTurnDirections {
-						drawingStates[i].Angle += drawingStates[i].TurningAngle
-					} else {
-						drawingStates[i].Angle -= drawingStates[i].TurningAngle
-					}
+				x0, y0 := drawingStates[i].X, drawingStates[i].Y
+				x1 := x0 + length*math.Cos(drawingStates[i].Angle*math.Pi/180)
+				y1 := y0 + length*math.Sin(drawingStates[i].Angle*math.Pi/180)
+				if c == 'F' || polygonOpen {
+					bounds.add(x0, y0, drawingStates[i].LineWidth)
+					bounds.add(x1, y1, drawingStates[i].LineWidth)
 				}
-			case '-':
-				{
-					if drawingStates[i].SwapTurnDirections {
-						drawingStates[i].Angle -= drawingStates[i].TurningAngle
-					} else {
-						drawingStates[i].Angle += drawingStates[i].TurningAngle
+				if gc != nil && polygonOpen {
+					// the moves of the turtle are the edges of the polygon
+					if !polygonStarted {
+						gc.MoveTo(view.apply(x0, y0))
+						polygonStarted = true
 					}
+					gc.LineTo(view.apply(x1, y1))
+				} else if gc != nil && c == 'F' {
+					gc.SetLineWidth(drawingStates[i].LineWidth)
+					gc.SetStrokeColor(strokeColor())
+					gc.BeginPath()
+					gc.MoveTo(view.apply(x0, y0))
+					gc.LineTo(view.apply(x1, y1))
+					gc.Stroke()
 				}
-			case '|':
-				{
-					if drawingStates[i].Angle >= 180 {
-						drawingStates[i].Angle -= 180.0
-					} else {
-						drawingStates[i].Angle += 180.0
-					}
+				drawingStates[i].X = x1
+				drawingStates[i].Y = y1
+				drawingStates[i].PathLength += math.Abs(length)
+				bounds.MaxPathLength = math.Max(bounds.MaxPathLength, drawingStates[i].PathLength)
+			}
+		case '+':
+			{
+				turningAngle := drawingStates[i].TurningAngle
+				if len(parameters) > 0 {
+					turningAngle = parameters[0]
 				}
-			case '[':
-				{
-					newDrawingState := State{
-						Angle:              drawingStates[i].Angle,
-						LineWidth:          drawingStates[i].LineWidth,
-						LineLength:         drawingStates[i].LineLength,
-						TurningAngle:       drawingStates[i].TurningAngle,
-						SwapTurnDirections: drawingStates[i].SwapTurnDirections,
-						X:                  drawingStates[i].X,
-						Y:                  drawingStates[i].Y,
-					}
-					if i+1 < n {
-						drawingStates[i+1] = newDrawingState
-					} else {
-						drawingStates = append(drawingStates, newDrawingState)
-						n++
-					}
-					i++
+				if drawingStates[i].SwapTurnDirections {
+					drawingStates[i].Angle += turningAngle
+				} else {
+					drawingStates[i].Angle -= turningAngle
 				}
-			case ']':
-				{
-					if i > 0 {
-						i--
-					}
+			}
+		case '-':
+			{
+				turningAngle := drawingStates[i].TurningAngle
+				if len(parameters) > 0 {
+					turningAngle = parameters[0]
+				}
+				if drawingStates[i].SwapTurnDirections {
+					drawingStates[i].Angle -= turningAngle
+				} else {
+					drawingStates[i].Angle += turningAngle
+				}
+			}
+		case '|':
+			{
+				if drawingStates[i].Angle >= 180 {
+					drawingStates[i].Angle -= 180.0
+				} else {
+					drawingStates[i].Angle += 180.0
+				}
+			}
+		case '[':
+			{
+				newDrawingState := drawingStates[i]
+				if i+1 < n {
+					drawingStates[i+1] = newDrawingState
+				} else {
+					drawingStates = append(drawingStates, newDrawingState)
+					n++
+				}
+				i++
+				if i > bounds.MaxDepth {
+					bounds.MaxDepth = i
+				}
+			}
+		case ']':
+			{
+				if i > 0 {
+					i--
 				}
-			case '#':
+			}
+		case '#':
+			if len(parameters) > 0 {
+				drawingStates[i].LineWidth += parameters[0]
+			} else {
 				drawingStates[i].LineWidth += props.LineWidthIncrement
-			case '!':
+			}
+		case '!':
+			if len(parameters) > 0 {
+				drawingStates[i].LineWidth = parameters[0]
+			} else {
 				drawingStates[i].LineWidth -= props.LineWidthIncrement
-			case '@':
-				{
-					if (!props.Focus || (props.Focus && round == 2)) && !polygonOpen {
-						// TODO: Draw a dot
+			}
+		case '@':
+			{
+				if !polygonOpen {
+					diameter := props.DotDiameter
+					if len(parameters) > 0 {
+						diameter = parameters[0]
+					}
+					bounds.add(drawingStates[i].X, drawingStates[i].Y, diameter)
+					if gc != nil {
+						x, y := view.apply(drawingStates[i].X, drawingStates[i].Y)
+						gc.SetFillColor(strokeColor())
+						gc.BeginPath()
+						draw2dkit.Circle(gc, x, y, diameter/2)
+						gc.Close()
+						gc.Fill()
 					}
 				}
-			case '{':
-				{
-					if !polygonOpen {
-						polygonOpen = true
-						if !props.Focus || (props.Focus && round == 2) {
-							gc.SetFillColor(color)
-							gc.SetStrokeColor(color)
-							gc.SetLineWidth(drawingStates[i].LineWidth)
-							gc.BeginPath()
+			}
+		case '{':
+			{
+				if !polygonOpen {
+					polygonOpen, polygonStarted = true, false
+					if gc != nil {
+						lineColor := strokeColor()
+						fillColor := lineColor
+						if props.UseFillColor {
+							fillColor = props.FillColor
 						}
+						gc.SetFillColor(fillColor)
+						gc.SetStrokeColor(lineColor)
+						gc.SetLineWidth(drawingStates[i].LineWidth)
+						gc.BeginPath()
 					}
 				}
-			case '}':
-				{
-					if polygonOpen {
-						polygonOpen = false
-						if (!props.Focus && round == 1) || (props.Focus && round == 2) {
-							gc.Close()
-							gc.FillStroke()
-						}
+			}
+		case '}':
+			{
+				if polygonOpen {
+					polygonOpen = false
+					if gc != nil {
+						gc.Close()
+						gc.FillStroke()
 					}
 				}
-			case '>':
+			}
+		case '>':
+			if len(parameters) > 0 {
+				drawingStates[i].LineLength *= parameters[0]
+			} else {
 				drawingStates[i].LineLength *= props.LineLengthScaleFactor
-			case '<':
+			}
+		case '<':
+			if len(parameters) > 0 {
+				drawingStates[i].LineLength /= parameters[0]
+			} else {
 				drawingStates[i].LineLength /= props.LineLengthScaleFactor
-			case '&':
-				drawingStates[i].SwapTurnDirections = !drawingStates[i].SwapTurnDirections
-			case '(':
-				drawingStates[i].TurningAngle -= props.TurningAngleIncrement
-			case ')':
-				drawingStates[i].TurningAngle += props.TurningAngleIncrement
 			}
+		case '&':
+			drawingStates[i].SwapTurnDirections = !drawingStates[i].SwapTurnDirections
+		case '(':
+			drawingStates[i].TurningAngle -= props.TurningAngleIncrement
+		case ')':
+			drawingStates[i].TurningAngle += props.TurningAngleIncrement
+		case '\'':
+			if len(parameters) > 0 {
+				drawingStates[i].ColorIndex = int(parameters[0])
+			} else {
+				drawingStates[i].ColorIndex++
+			}
+		case ';':
+			if len(parameters) > 0 {
+				drawingStates[i].ColorIndex = int(parameters[0])
+			} else {
+				drawingStates[i].ColorIndex--
+			}
+		}
+	})
+	if err != nil {
+		return bounds, err
+	}
+	if gc != nil && polygonOpen {
+		gc.Close()
+		gc.FillStroke()
+	}
+	return bounds, nil
+}
+
+// Retrieves the color of the lines of a turtle with the given color index,
+// branch depth and path length, where the measured bounds scale the depth
+// and length colorings.
+func (props *LindenmayerSystem) getColor(solidColor color.RGBA, colorIndex, depth int, pathLength float64, measured lindenmayerBounds) color.RGBA {
+	var pos float64
+	switch props.Coloring {
+	case LSYSTEM_COLORING_INDEX:
+		count := props.ColorCount
+		if count < 2 {
+			count = 2
 		}
-		if !props.Focus || (props.Focus && round == 2) && polygonOpen {
-			gc.Close()
-			gc.FillStroke()
+		index := colorIndex % count
+		if index < 0 {
+			index += count
 		}
-		if !props.Focus {
-			break
+		pos = float64(index) / float64(count-1)
+	case LSYSTEM_COLORING_DEPTH:
+		if measured.MaxDepth > 0 {
+			pos = float64(depth) / float64(measured.MaxDepth)
+		}
+	case LSYSTEM_COLORING_LENGTH:
+		if measured.MaxPathLength > 0 {
+			pos = pathLength / measured.MaxPathLength
 		}
+	default:
+		return solidColor
 	}
+	paletteColor, _ := props.ColorPalette.GetColor(pos)
+	return helpers.ToRGBAColor(paletteColor)
 }
 
-// Builds the image generation string for this Lindenmayer system.
-func (props *LindenmayerSystem) BuildGenerator() []rune {
-	previousString := []rune(props.Axiom)
+// Builds the image generation modules for this Lindenmayer system.
+func (props *LindenmayerSystem) BuildGenerator() ([]LindenmayerModule, error) {
+	rng := rand.New(rand.NewSource(props.Seed))
+	previousModules, err := ParseLindenmayerAxiom(props.Axiom)
+	if err != nil {
+		return nil, err
+	}
 	for i := 0; i < props.Iterations; i++ {
-		var newString []rune
-	str_replacement:
-		for _, c := range previousString {
-			for variable, replacement := range props.RewriteRules {
-				if variable == c {
-					newString = append(newString, []rune(replacement)...)
-					continue str_replacement
+		var newModules []LindenmayerModule
+		for j, module := range previousModules {
+			rules, ok := props.RewriteRules[module.Symbol]
+			if !ok {
+				newModules = append(newModules, module)
+				continue
+			}
+			applied := false
+			for k := range rules {
+				values, ok := props.matchLindenmayerRule(&rules[k], previousModules, j)
+				if !ok {
+					continue
 				}
+				successor := chooseLindenmayerSuccessor(rules[k].Successors, rng)
+				newModules = append(newModules, evaluateLindenmayerSuccessor(successor, values)...)
+				applied = true
+				break
+			}
+			if !applied {
+				newModules = append(newModules, module)
 			}
-			newString = append(newString, c)
 		}
-		previousString = newString
+		if props.MaxSymbols > 0 && int64(len(newModules)) > props.MaxSymbols {
+			return nil, props.symbolBudgetError()
+		}
+		previousModules = newModules
+	}
+	for i := 0; i < len(previousModules); i++ {
+		previousModules[i].Symbol = props.turtleSymbol(previousModules[i].Symbol)
+	}
+	return previousModules, nil
+}
+
+// Passes the modules of the derivation of this Lindenmayer system to the
+// visit function in order. The rules are applied depth-first, so only the
+// unfinished successors of each iteration are kept instead of the whole
+// generator, unless some rules have contexts. The derivation must fit in the
+// symbol budget.
+func (props *LindenmayerSystem) Expand(visit func(module LindenmayerModule)) error {
+	err := props.CheckSymbolBudget()
+	if err != nil {
+		return err
 	}
-	for i := 0; i < len(previousString); i++ {
-		c := previousString[i]
-		if strings.ContainsRune(props.DrawSymbols, c) {
-			previousString[i] = 'F'
-		} else if strings.ContainsRune(props.SkipSymbols, c) {
-			previousString[i] = 'f'
+	if props.IsContextSensitive() {
+		generator, err := props.BuildGenerator()
+		if err != nil {
+			return err
 		}
+		for _, module := range generator {
+			visit(module)
+		}
+		return nil
+	}
+	axiom, err := ParseLindenmayerAxiom(props.Axiom)
+	if err != nil {
+		return err
+	}
+	rng := rand.New(rand.NewSource(props.Seed))
+	stack := []lindenmayerFrame{{modules: axiom}}
+	var count, rewritten int64
+	for len(stack) > 0 {
+		top := len(stack) - 1
+		if len(stack[top].modules) == 0 {
+			stack = stack[:top]
+			continue
+		}
+		module, depth := stack[top].modules[0], stack[top].depth
+		stack[top].modules = stack[top].modules[1:]
+		if depth < props.Iterations {
+			successor := props.findLindenmayerSuccessor(module, rng)
+			if successor != nil {
+				// the rewritten modules are limited too, since the modules
+				// of a system can grow and then be erased
+				rewritten++
+				if props.MaxSymbols > 0 && rewritten > props.MaxSymbols {
+					return fmt.Errorf("The system rewrites more than %d symbols in %d iterations. Use fewer iterations or a bigger symbol budget", props.MaxSymbols, props.Iterations)
+				}
+				stack = append(stack, lindenmayerFrame{modules: successor, depth: depth + 1})
+				continue
+			}
+		}
+		// a module that no rule applies to stays the same in the remaining
+		// iterations since its parameters don't change
+		count++
+		if props.MaxSymbols > 0 && count > props.MaxSymbols {
+			return props.symbolBudgetError()
+		}
+		module.Symbol = props.turtleSymbol(module.Symbol)
+		visit(module)
 	}
-	return previousString
+	return nil
+}
+
+// Represents the modules of a successor that are left to be expanded in a
+// depth-first derivation.
+type lindenmayerFrame struct {
+	modules []LindenmayerModule
+	depth   int
 }
 
-// Converts a comma-separated list of rewrite rules to a map of
-// variable and rewrite string.
-func ParseLindenmayerRules(txt string) (map[rune]string, error) {
-	values, err := helpers.GetCSV(txt)
+// Applies the first rule of a module without contexts that meets its
+// condition. It's nil if no rule applies.
+func (props *LindenmayerSystem) findLindenmayerSuccessor(module LindenmayerModule, rng *rand.Rand) []LindenmayerModule {
+	rules := props.RewriteRules[module.Symbol]
+	for k := range rules {
+		values, ok := props.matchLindenmayerRule(&rules[k], []LindenmayerModule{module}, 0)
+		if ok {
+			return evaluateLindenmayerSuccessor(chooseLindenmayerSuccessor(rules[k].Successors, rng), values)
+		}
+	}
+	return nil
+}
+
+// Checks if any rule of this system has a left or right context.
+func (props *LindenmayerSystem) IsContextSensitive() bool {
+	for _, rules := range props.RewriteRules {
+		for _, rule := range rules {
+			if len(rule.LeftContext) > 0 || len(rule.RightContext) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Retrieves the symbol the turtle reads for a symbol of the derivation.
+func (props *LindenmayerSystem) turtleSymbol(c rune) rune {
+	if strings.ContainsRune(props.DrawSymbols, c) {
+		return 'F'
+	} else if strings.ContainsRune(props.SkipSymbols, c) {
+		return 'f'
+	}
+	return c
+}
+
+// Estimates the largest number of modules the derivation of this Lindenmayer
+// system can have. It assumes that every condition is met and that the
+// longest successor of a stochastic rule is always chosen. The estimate stops
+// growing once it's over the limit.
+func (props *LindenmayerSystem) EstimateLength(limit float64) (float64, error) {
+	axiom, err := ParseLindenmayerAxiom(props.Axiom)
 	if err != nil {
-		return nil, err
+		return 0, err
+	}
+	lengths := map[rune]float64{}
+	for symbol := range props.RewriteRules {
+		lengths[symbol] = 1
 	}
-	rules := make(map[rune]string, len(values))
-	for i := 0; i < len(values); i++ {
-		rule := strings.Trim(values[i], helpers.WHITESPACE_CUTSET)
-		if strings.ContainsRune(rule, '=') {
-			before, expression, _ := strings.Cut(rule, "=")
-			variable := []rune(strings.Trim(before, helpers.WHITESPACE_CUTSET))
-			if len(variable) == 1 {
-				rules[variable[0]] = expression
+	total := func() float64 {
+		sum := 0.0
+		for _, module := range axiom {
+			if length, ok := lengths[module.Symbol]; ok {
+				sum += length
 			} else {
-				return nil, errors.New("The variable must be a single character")
+				sum++
 			}
-		} else {
-			return nil, errors.New("Invalid rewrite rule")
+		}
+		return sum
+	}
+	for i := 0; i < props.Iterations && total() <= limit; i++ {
+		newLengths := make(map[rune]float64, len(lengths))
+		changed := false
+		for symbol, rules := range props.RewriteRules {
+			longest := lengths[symbol]
+			for _, rule := range rules {
+				for _, successor := range rule.Successors {
+					sum := 0.0
+					for _, production := range successor.Modules {
+						if length, ok := lengths[production.Symbol]; ok {
+							sum += length
+						} else {
+							sum++
+						}
+					}
+					longest = math.Max(longest, sum)
+				}
+			}
+			newLengths[symbol] = longest
+			changed = changed || longest != lengths[symbol]
+		}
+		lengths = newLengths
+		if !changed {
+			break
 		}
 	}
+	return total(), nil
+}
+
+// Checks if the estimated length of the derivation of this Lindenmayer
+// system is within its symbol budget. A budget of 0 has no limit. Systems
+// with conditions pass, since conditions usually stop the growth well before
+// the estimate, and their derivations are checked as they're expanded.
+func (props *LindenmayerSystem) CheckSymbolBudget() error {
+	if props.MaxSymbols <= 0 || props.hasConditions() {
+		return nil
+	}
+	length, err := props.EstimateLength(float64(props.MaxSymbols))
+	if err != nil {
+		return err
+	}
+	if length > float64(props.MaxSymbols) {
+		return props.symbolBudgetError()
+	}
+	return nil
+}
+
+func (props *LindenmayerSystem) symbolBudgetError() error {
+	return fmt.Errorf("The system can have more than %d symbols after %d iterations. Use fewer iterations or a bigger symbol budget", props.MaxSymbols, props.Iterations)
+}
+
+// Checks if any rule of this system has a condition.
+func (props *LindenmayerSystem) hasConditions() bool {
+	for _, rules := range props.RewriteRules {
+		for _, rule := range rules {
+			if rule.Condition != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Checks if a rule applies to the module at the given index and retrieves
+// the values of the parameters of the predecessor, the left context and the
+// right context, in that order.
+func (props *LindenmayerSystem) matchLindenmayerRule(rule *LindenmayerRule, modules []LindenmayerModule, index int) ([]float64, bool) {
+	if len(modules[index].Parameters) != len(rule.Predecessor.Parameters) {
+		return nil, false
+	}
+	values := append([]float64{}, modules[index].Parameters...)
+	if len(rule.LeftContext) > 0 {
+		leftValues := []float64{}
+		pos := index - 1
+		for k := len(rule.LeftContext) - 1; k >= 0; k-- {
+			pos = props.previousContextModule(modules, pos)
+			if pos < 0 || !rule.LeftContext[k].matches(modules[pos]) {
+				return nil, false
+			}
+			leftValues = append(append([]float64{}, modules[pos].Parameters...), leftValues...)
+			pos--
+		}
+		values = append(values, leftValues...)
+	}
+	pos := index + 1
+	for _, pattern := range rule.RightContext {
+		pos = props.nextContextModule(modules, pos)
+		if pos >= len(modules) || !pattern.matches(modules[pos]) {
+			return nil, false
+		}
+		values = append(values, modules[pos].Parameters...)
+		pos++
+	}
+	if rule.Condition != nil && rule.Condition.Evaluate(values) == 0 {
+		return nil, false
+	}
+	return values, true
+}
+
+// Finds the index of the closest module at or before the given index that
+// can be a left context, which skips the ignored symbols and the branches
+// and leaves the branch the modules are in. It's negative if there's none.
+func (props *LindenmayerSystem) previousContextModule(modules []LindenmayerModule, pos int) int {
+	for pos >= 0 {
+		switch c := modules[pos].Symbol; {
+		case c == ']':
+			depth := 1
+			for pos--; pos >= 0 && depth > 0; pos-- {
+				if modules[pos].Symbol == ']' {
+					depth++
+				} else if modules[pos].Symbol == '[' {
+					depth--
+				}
+			}
+		case c == '[' || strings.ContainsRune(props.IgnoreSymbols, c):
+			pos--
+		default:
+			return pos
+		}
+	}
+	return pos
+}
+
+// Finds the index of the closest module at or after the given index that
+// can be a right context, which skips the ignored symbols and the branches.
+// It's the number of modules if there's none before the branch ends.
+func (props *LindenmayerSystem) nextContextModule(modules []LindenmayerModule, pos int) int {
+	for pos < len(modules) {
+		switch c := modules[pos].Symbol; {
+		case c == '[':
+			depth := 1
+			for pos++; pos < len(modules) && depth > 0; pos++ {
+				if modules[pos].Symbol == '[' {
+					depth++
+				} else if modules[pos].Symbol == ']' {
+					depth--
+				}
+			}
+		case c == ']':
+			return len(modules)
+		case strings.ContainsRune(props.IgnoreSymbols, c):
+			pos++
+		default:
+			return pos
+		}
+	}
+	return pos
+}
+
+// Checks if a module has the symbol and the number of parameters of this
+// pattern.
+func (pattern *LindenmayerPattern) matches(module LindenmayerModule) bool {
+	return pattern.Symbol == module.Symbol && len(pattern.Parameters) == len(module.Parameters)
+}
+
+// Creates the modules of a successor for the values of the parameters of the
+// left side of its rule.
+func evaluateLindenmayerSuccessor(successor *LindenmayerSuccessor, values []float64) []LindenmayerModule {
+	modules := make([]LindenmayerModule, len(successor.Modules))
+	for i, production := range successor.Modules {
+		modules[i].Symbol = production.Symbol
+		if len(production.Parameters) > 0 {
+			modules[i].Parameters = make([]float64, len(production.Parameters))
+			for j := range production.Parameters {
+				modules[i].Parameters[j] = production.Parameters[j].Evaluate(values)
+			}
+		}
+	}
+	return modules
+}
+
+// Picks one of the right sides of a rewrite rule by their probabilities.
+func chooseLindenmayerSuccessor(successors []LindenmayerSuccessor, rng *rand.Rand) *LindenmayerSuccessor {
+	if len(successors) == 1 {
+		return &successors[0]
+	}
+	choice := rng.Float64()
+	sum := 0.0
+	for i := range successors {
+		sum += successors[i].Probability
+		if choice < sum {
+			return &successors[i]
+		}
+	}
+	return &successors[len(successors)-1]
+}
+
+// Converts the axiom of a Lindenmayer system to modules, where the
+// parameters of a module are given as constant expressions in parentheses
+// right after its symbol, such as F(10)+(45)F(10*sqrt(2)).
+func ParseLindenmayerAxiom(txt string) ([]LindenmayerModule, error) {
+	productions, err := parseLindenmayerProductions(txt, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid axiom: %s", err.Error())
+	}
+	return evaluateLindenmayerSuccessor(&LindenmayerSuccessor{Modules: productions}, nil), nil
+}
+
+// Converts a comma-separated list of rewrite rules to a map of the symbols
+// of the predecessors and their rewrite rules.
+//
+// A rule is written as [left context <] predecessor [> right context]
+// [: condition] -> successor, where = can be used instead of -> when there's
+// no condition or the condition has no =. The parameters of the predecessor
+// and the contexts are names, such as A(t), which the condition and the
+// parameters of the successor can use in expressions, such as
+// A(t): t>5 -> B(t-1)F(t).
+//
+// Rules with the same left side and condition are stochastic when any of
+// them has a probability, which is given in parentheses at the start of its
+// successor, such as F=(0.5)F[+F]F. The rules without a probability share
+// what is left of the probabilities of the left side. Otherwise, the last
+// of the rules replaces the others.
+func ParseLindenmayerRules(txt string) (map[rune][]LindenmayerRule, error) {
+	rules := map[rune][]LindenmayerRule{}
+	sides := map[string]*LindenmayerRule{}
+	var sideOrder []string
+	values, err := splitLindenmayerRules(txt)
+	if err != nil {
+		return nil, err
+	}
+	for _, value := range values {
+		ruleTxt := strings.Trim(value, helpers.WHITESPACE_CUTSET)
+		separator := "->"
+		arrow, equals := strings.Index(ruleTxt, "->"), strings.Index(ruleTxt, "=")
+		if arrow < 0 || (equals >= 0 && equals < arrow && !strings.Contains(ruleTxt[:equals], ":")) {
+			separator = "="
+		}
+		before, after, found := strings.Cut(ruleTxt, separator)
+		if !found {
+			return nil, fmt.Errorf("Invalid rewrite rule: %s", ruleTxt)
+		}
+		patternTxt, conditionTxt, hasCondition := strings.Cut(before, ":")
+		side := strings.Join(strings.Fields(before), "")
+		rule, ok := sides[side]
+		if !ok {
+			newRule, err := parseLindenmayerRuleSide(patternTxt)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid rewrite rule %s: %s", ruleTxt, err.Error())
+			}
+			if hasCondition {
+				condition, err := math_helpers.ParseRealExpression(conditionTxt, newRule.variables())
+				if err != nil {
+					return nil, fmt.Errorf("Invalid condition of %s: %s", ruleTxt, err.Error())
+				}
+				newRule.Condition = &condition
+			}
+			rule = &newRule
+			sides[side] = rule
+			sideOrder = append(sideOrder, side)
+		}
+		probability := math.NaN()
+		successorTxt := strings.Trim(after, helpers.WHITESPACE_CUTSET)
+		if strings.HasPrefix(successorTxt, "(") {
+			value, replacement, found := strings.Cut(successorTxt[1:], ")")
+			number, err := strconv.ParseFloat(strings.Trim(value, helpers.WHITESPACE_CUTSET), 64)
+			if found && err == nil {
+				if number < 0 || number > 1 {
+					return nil, fmt.Errorf("The probability of a rule of %s must be between 0 and 1", side)
+				}
+				probability = number
+				successorTxt = replacement
+			}
+		}
+		productions, err := parseLindenmayerProductions(successorTxt, rule.variables())
+		if err != nil {
+			return nil, fmt.Errorf("Invalid rewrite rule %s: %s", ruleTxt, err.Error())
+		}
+		rule.Successors = append(rule.Successors, LindenmayerSuccessor{Modules: productions, Probability: probability})
+	}
+	for _, side := range sideOrder {
+		rule := sides[side]
+		if !hasLindenmayerProbabilities(rule.Successors) {
+			rule.Successors = rule.Successors[len(rule.Successors)-1:]
+		}
+		err := normalizeLindenmayerSuccessors(side, rule.Successors)
+		if err != nil {
+			return nil, err
+		}
+		rules[rule.Predecessor.Symbol] = append(rules[rule.Predecessor.Symbol], *rule)
+	}
 	return rules, nil
 }
 
+// Creates the rewrite rule of a symbol without parameters or contexts,
+// which always replaces it with the given successor.
+func newLindenmayerRule(symbol rune, successor string) (LindenmayerRule, error) {
+	productions, err := parseLindenmayerProductions(successor, nil)
+	if err != nil {
+		return LindenmayerRule{}, err
+	}
+	return LindenmayerRule{
+		Predecessor: LindenmayerPattern{Symbol: symbol},
+		Successors:  []LindenmayerSuccessor{{Modules: productions, Probability: 1}},
+	}, nil
+}
+
+// Retrieves the names of the parameters of the predecessor, the left context
+// and the right context of this rule, in that order.
+func (rule *LindenmayerRule) variables() []string {
+	variables := append([]string{}, rule.Predecessor.Parameters...)
+	for _, pattern := range rule.LeftContext {
+		variables = append(variables, pattern.Parameters...)
+	}
+	for _, pattern := range rule.RightContext {
+		variables = append(variables, pattern.Parameters...)
+	}
+	return variables
+}
+
+// Splits rewrite rules at the commas that aren't in parentheses or double
+// quotes, which are removed from around a rule. A ( or ) without a matching
+// parenthesis is a turtle command and doesn't group commas.
+func splitLindenmayerRules(txt string) ([]string, error) {
+	chars := []rune(txt)
+	quoted := make([]bool, len(chars))
+	inQuotes := false
+	for i, c := range chars {
+		if c == '"' && (i == 0 || chars[i-1] != '\\') {
+			inQuotes = !inQuotes
+		}
+		quoted[i] = inQuotes
+	}
+	if inQuotes {
+		return nil, errors.New("Inconsistent double quotes in text")
+	}
+	matched := make([]bool, len(chars))
+	var opened []int
+	for i, c := range chars {
+		if quoted[i] {
+			continue
+		}
+		if c == '(' {
+			opened = append(opened, i)
+		} else if c == ')' && len(opened) > 0 {
+			matched[opened[len(opened)-1]], matched[i] = true, true
+			opened = opened[:len(opened)-1]
+		}
+	}
+	var values []string
+	depth, start := 0, 0
+	for i, c := range chars {
+		switch {
+		case quoted[i]:
+		case c == '(' && matched[i]:
+			depth++
+		case c == ')' && matched[i]:
+			depth--
+		case c == ',' && depth == 0:
+			values = append(values, unquoteLindenmayerRule(string(chars[start:i])))
+			start = i + 1
+		}
+	}
+	if strings.Trim(string(chars[start:]), helpers.WHITESPACE_CUTSET) != "" || len(values) > 0 {
+		values = append(values, unquoteLindenmayerRule(string(chars[start:])))
+	}
+	return values, nil
+}
+
+// Removes the double quotes from around a rewrite rule.
+func unquoteLindenmayerRule(txt string) string {
+	rule := strings.Trim(txt, helpers.WHITESPACE_CUTSET)
+	if len(rule) > 1 && strings.HasPrefix(rule, "\"") && strings.HasSuffix(rule, "\"") {
+		return rule[1 : len(rule)-1]
+	}
+	return txt
+}
+
+// Parses the predecessor and the contexts of the left side of a rewrite
+// rule, such as a(x) < b(y) > c.
+func parseLindenmayerRuleSide(txt string) (LindenmayerRule, error) {
+	patterns, err := parseLindenmayerPatterns(txt)
+	if err != nil {
+		return LindenmayerRule{}, err
+	}
+	left, right := -1, len(patterns)
+	if len(patterns) > 1 {
+		for i, pattern := range patterns {
+			if pattern.Symbol == '<' && len(pattern.Parameters) == 0 && left < 0 {
+				left = i
+			} else if pattern.Symbol == '>' && len(pattern.Parameters) == 0 {
+				right = i
+			}
+		}
+	}
+	if right-left != 2 {
+		return LindenmayerRule{}, errors.New("The left side must have exactly one predecessor between the contexts")
+	}
+	rule := LindenmayerRule{Predecessor: patterns[left+1]}
+	if left >= 0 {
+		rule.LeftContext = patterns[:left]
+	}
+	if right < len(patterns) {
+		rule.RightContext = patterns[right+1:]
+	}
+	for _, pattern := range append(append([]LindenmayerPattern{}, rule.LeftContext...), rule.RightContext...) {
+		if pattern.Symbol == '[' || pattern.Symbol == ']' {
+			return LindenmayerRule{}, errors.New("The contexts can't have branches")
+		}
+	}
+	return rule, nil
+}
+
+// Parses symbols with the names of their parameters, such as A(x,y)B.
+func parseLindenmayerPatterns(txt string) ([]LindenmayerPattern, error) {
+	var patterns []LindenmayerPattern
+	return patterns, scanLindenmayerModules(txt, func(symbol rune, arguments []string) error {
+		pattern := LindenmayerPattern{Symbol: symbol}
+		for _, argument := range arguments {
+			name := strings.Trim(argument, helpers.WHITESPACE_CUTSET)
+			if !isLindenmayerParameterName(name) {
+				return fmt.Errorf("Invalid parameter name of %c: %s", symbol, name)
+			}
+			pattern.Parameters = append(pattern.Parameters, name)
+		}
+		patterns = append(patterns, pattern)
+		return nil
+	})
+}
+
+// Parses symbols with expressions of the given variables as their
+// parameters, such as F(l*2)+(a).
+func parseLindenmayerProductions(txt string, variables []string) ([]LindenmayerProduction, error) {
+	var productions []LindenmayerProduction
+	return productions, scanLindenmayerModules(txt, func(symbol rune, arguments []string) error {
+		production := LindenmayerProduction{Symbol: symbol}
+		for _, argument := range arguments {
+			expression, err := math_helpers.ParseRealExpression(argument, variables)
+			if err != nil {
+				return fmt.Errorf("Invalid parameter of %c: %s", symbol, err.Error())
+			}
+			production.Parameters = append(production.Parameters, expression)
+		}
+		productions = append(productions, production)
+		return nil
+	})
+}
+
+// Passes each symbol of a string of modules to the visit function along with
+// the comma-separated parameters in the parentheses right after it. When the
+// visit function rejects the parameters, which it does without keeping the
+// symbol, the parentheses are passed as the symbols ( and ) instead, so that
+// systems that use them to change the turning angle, such as F(F), still
+// work. White spaces between modules are ignored.
+func scanLindenmayerModules(txt string, visit func(symbol rune, arguments []string) error) error {
+	chars := []rune(txt)
+	for i := 0; i < len(chars); i++ {
+		symbol := chars[i]
+		if unicode.IsSpace(symbol) {
+			continue
+		}
+		if arguments, end, ok := scanLindenmayerArguments(chars, i+1); ok && visit(symbol, arguments) == nil {
+			i = end
+			continue
+		}
+		err := visit(symbol, nil)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Retrieves the comma-separated arguments in the parentheses that start at
+// the given position and the position of the closing parenthesis. ok is
+// false when there are no parentheses at the position or they aren't closed.
+func scanLindenmayerArguments(chars []rune, start int) (arguments []string, end int, ok bool) {
+	if start >= len(chars) || chars[start] != '(' {
+		return nil, start, false
+	}
+	depth, argumentStart := 0, start+1
+	for j := start; j < len(chars); j++ {
+		if chars[j] == '(' {
+			depth++
+		} else if chars[j] == ')' {
+			depth--
+		} else if chars[j] == ',' && depth == 1 {
+			arguments = append(arguments, string(chars[argumentStart:j]))
+			argumentStart = j + 1
+		}
+		if depth == 0 {
+			return append(arguments, string(chars[argumentStart:j])), j, true
+		}
+	}
+	return nil, start, false
+}
+
+// Checks if a name is a letter followed by letters, digits or underscores.
+func isLindenmayerParameterName(name string) bool {
+	for i, c := range name {
+		if !unicode.IsLetter(c) && (i == 0 || (!unicode.IsDigit(c) && c != '_')) {
+			return false
+		}
+	}
+	return name != ""
+}
+
+// Checks if any of the right sides of a rewrite rule has a probability.
+func hasLindenmayerProbabilities(successors []LindenmayerSuccessor) bool {
+	for _, successor := range successors {
+		if !math.IsNaN(successor.Probability) {
+			return true
+		}
+	}
+	return false
+}
+
+// Fills the missing (NaN) probabilities of the right sides of a rewrite rule
+// and scales the probabilities to sum to exactly 1.
+func normalizeLindenmayerSuccessors(side string, successors []LindenmayerSuccessor) error {
+	givenSum, missing := 0.0, 0
+	for _, successor := range successors {
+		if math.IsNaN(successor.Probability) {
+			missing++
+		} else {
+			givenSum += successor.Probability
+		}
+	}
+	if missing > 0 {
+		remaining := 1 - givenSum
+		if remaining <= 0 {
+			return fmt.Errorf("The probabilities of the rules of %s sum to %.4g, which leaves nothing for the rules without a probability", side, givenSum)
+		}
+		for i := range successors {
+			if math.IsNaN(successors[i].Probability) {
+				successors[i].Probability = remaining / float64(missing)
+			}
+		}
+	} else if math.Abs(givenSum-1) > LSYSTEM_PROBABILITY_TOLERANCE {
+		return fmt.Errorf("The probabilities of the rules of %s sum to %.4g instead of 1", side, givenSum)
+	}
+	sum := 0.0
+	for _, successor := range successors {
+		sum += successor.Probability
+	}
+	for i := range successors {
+		successors[i].Probability /= sum
+	}
+	return nil
+}
+
+// Checks if a coloring is supported by the Lindenmayer system.
+func IsValidLSystemColoring(txt string) bool {
+	return txt == LSYSTEM_COLORING_SOLID || txt == LSYSTEM_COLORING_INDEX || txt == LSYSTEM_COLORING_DEPTH || txt == LSYSTEM_COLORING_LENGTH
+}
+
+// Checks if a way of fitting a Lindenmayer system to the image is supported.
+func IsValidLSystemFit(txt string) bool {
+	return txt == LSYSTEM_FIT_CONTAIN || txt == LSYSTEM_FIT_COVER
+}
+
 // Retrieves the cartesian position of a point given a position.
 func ParseLSystemPosition(txt string, width, height float64) (x, y float64, err error) {
 	switch txt {
@@ -0,0 +1,480 @@
+package fractals
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/B3zaleel/fractage/src/helpers"
+	"github.com/llgcode/draw2d"
+	"github.com/llgcode/draw2d/draw2dkit"
+)
+
+const (
+	LSYSTEM_MODE_2D                 = "2d"
+	LSYSTEM_MODE_3D                 = "3d"
+	LSYSTEM_PROJECTION_ORTHOGRAPHIC = "orthographic"
+	LSYSTEM_PROJECTION_PERSPECTIVE  = "perspective"
+	LSYSTEM_MESH_LINE               = "line"
+	LSYSTEM_MESH_TUBE               = "tube"
+	// The number of sides of the tubes around the lines of a tube mesh.
+	LSYSTEM_TUBE_SIDES = 8
+)
+
+// Represents a point or a direction in space.
+type vector3 [3]float64
+
+func (v vector3) add(w vector3) vector3 {
+	return vector3{v[0] + w[0], v[1] + w[1], v[2] + w[2]}
+}
+
+func (v vector3) scale(k float64) vector3 {
+	return vector3{v[0] * k, v[1] * k, v[2] * k}
+}
+
+func (v vector3) cross(w vector3) vector3 {
+	return vector3{v[1]*w[2] - v[2]*w[1], v[2]*w[0] - v[0]*w[2], v[0]*w[1] - v[1]*w[0]}
+}
+
+func (v vector3) length() float64 {
+	return math.Sqrt(v[0]*v[0] + v[1]*v[1] + v[2]*v[2])
+}
+
+// Rotates the directions a and b in their plane by the given angle in
+// degrees, which turns a towards b for positive angles.
+func rotateFrame(a, b *vector3, angle float64) {
+	sin, cos := math.Sincos(angle * math.Pi / 180)
+	newA := a.scale(cos).add(b.scale(sin))
+	newB := b.scale(cos).add(a.scale(-sin))
+	*a, *b = newA, newB
+}
+
+// Represents a drawing state of the turtle in space, which moves along its
+// heading and turns around its heading, left and up directions.
+type SpaceState struct {
+	Position     vector3
+	Heading      vector3
+	Left         vector3
+	Up           vector3
+	LineWidth    float64
+	LineLength   float64
+	TurningAngle float64
+	ColorIndex   int
+	PathLength   float64
+}
+
+// Represents a line (two points), a dot (one point) or a polygon (more points)
+// drawn by the turtle in space.
+type lindenmayerShape struct {
+	Points []vector3
+	// The line width, or the diameter of a dot.
+	Width      float64
+	IsDot      bool
+	ColorIndex int
+	Depth      int
+	PathLength float64
+}
+
+// Represents a shape projected onto the image, where Z is the depth of its
+// center towards the camera.
+type projectedShape struct {
+	Shape  *lindenmayerShape
+	Points []helpers.Point
+	Scale  float64
+	Z      float64
+}
+
+// Moves the turtle in space through the derivation of this Lindenmayer
+// system and retrieves the shapes it draws, along with the largest branch
+// depth and path length.
+func (props *LindenmayerSystem) buildShapes() ([]lindenmayerShape, lindenmayerBounds, error) {
+	var shapes []lindenmayerShape
+	bounds := lindenmayerBounds{Empty: true}
+	sin, cos := math.Sincos(props.Angle * math.Pi / 180)
+	// the angle of the 2D turtle is measured clockwise in the image, whose y
+	// axis points down, while the y axis of the space points up
+	drawingStates := []SpaceState{{
+		Heading:      vector3{cos, -sin, 0},
+		Left:         vector3{sin, cos, 0},
+		Up:           vector3{0, 0, 1},
+		LineWidth:    props.LineWidth,
+		LineLength:   props.LineLength,
+		TurningAngle: props.TurningAngle,
+	}}
+	i := 0
+	var polygon *lindenmayerShape
+	newShape := func(points []vector3, width float64) lindenmayerShape {
+		return lindenmayerShape{
+			Points:     points,
+			Width:      width,
+			ColorIndex: drawingStates[i].ColorIndex,
+			Depth:      i,
+			PathLength: drawingStates[i].PathLength,
+		}
+	}
+	err := props.Expand(func(module LindenmayerModule) {
+		c, parameters := module.Symbol, module.Parameters
+		state := &drawingStates[i]
+		angle := state.TurningAngle
+		if len(parameters) > 0 {
+			angle = parameters[0]
+		}
+		switch c {
+		case 'F', 'f':
+			length := state.LineLength
+			if len(parameters) > 0 {
+				length = parameters[0]
+			}
+			start := state.Position
+			state.Position = start.add(state.Heading.scale(length))
+			if polygon != nil {
+				if len(polygon.Points) == 0 {
+					polygon.Points = append(polygon.Points, start)
+				}
+				polygon.Points = append(polygon.Points, state.Position)
+			} else if c == 'F' {
+				shapes = append(shapes, newShape([]vector3{start, state.Position}, state.LineWidth))
+			}
+			state.PathLength += math.Abs(length)
+			bounds.MaxPathLength = math.Max(bounds.MaxPathLength, state.PathLength)
+		case '+':
+			rotateFrame(&state.Heading, &state.Left, angle)
+		case '-':
+			rotateFrame(&state.Heading, &state.Left, -angle)
+		case '&':
+			rotateFrame(&state.Heading, &state.Up, -angle)
+		case '^':
+			rotateFrame(&state.Heading, &state.Up, angle)
+		case '\\':
+			rotateFrame(&state.Left, &state.Up, -angle)
+		case '/':
+			rotateFrame(&state.Left, &state.Up, angle)
+		case '|':
+			rotateFrame(&state.Heading, &state.Left, 180)
+		case '$':
+			// rolls the turtle so that its left direction is horizontal
+			left := vector3{0, 1, 0}.cross(state.Heading)
+			if left.length() > 1e-9 {
+				state.Left = left.scale(1 / left.length())
+				state.Up = state.Heading.cross(state.Left)
+			}
+		case '[':
+			drawingStates = append(drawingStates[:i+1], drawingStates[i])
+			i++
+			if i > bounds.MaxDepth {
+				bounds.MaxDepth = i
+			}
+		case ']':
+			if i > 0 {
+				i--
+			}
+		case '#':
+			if len(parameters) > 0 {
+				state.LineWidth += parameters[0]
+			} else {
+				state.LineWidth += props.LineWidthIncrement
+			}
+		case '!':
+			if len(parameters) > 0 {
+				state.LineWidth = parameters[0]
+			} else {
+				state.LineWidth -= props.LineWidthIncrement
+			}
+		case '@':
+			if polygon == nil {
+				diameter := props.DotDiameter
+				if len(parameters) > 0 {
+					diameter = parameters[0]
+				}
+				dot := newShape([]vector3{state.Position}, diameter)
+				dot.IsDot = true
+				shapes = append(shapes, dot)
+			}
+		case '{':
+			if polygon == nil {
+				shape := newShape(nil, state.LineWidth)
+				polygon = &shape
+			}
+		case '}':
+			if polygon != nil {
+				if len(polygon.Points) > 2 {
+					shapes = append(shapes, *polygon)
+				}
+				polygon = nil
+			}
+		case '>':
+			if len(parameters) > 0 {
+				state.LineLength *= parameters[0]
+			} else {
+				state.LineLength *= props.LineLengthScaleFactor
+			}
+		case '<':
+			if len(parameters) > 0 {
+				state.LineLength /= parameters[0]
+			} else {
+				state.LineLength /= props.LineLengthScaleFactor
+			}
+		case '(':
+			state.TurningAngle -= props.TurningAngleIncrement
+		case ')':
+			state.TurningAngle += props.TurningAngleIncrement
+		case '\'':
+			if len(parameters) > 0 {
+				state.ColorIndex = int(parameters[0])
+			} else {
+				state.ColorIndex++
+			}
+		case ';':
+			if len(parameters) > 0 {
+				state.ColorIndex = int(parameters[0])
+			} else {
+				state.ColorIndex--
+			}
+		}
+	})
+	if err != nil {
+		return nil, bounds, err
+	}
+	if polygon != nil && len(polygon.Points) > 2 {
+		shapes = append(shapes, *polygon)
+	}
+	return shapes, bounds, nil
+}
+
+// Projects the shapes onto the image plane of a camera that looks at their
+// center from the azimuth and elevation of this Lindenmayer system.
+func (props *LindenmayerSystem) projectShapes(shapes []lindenmayerShape) []projectedShape {
+	var minPoint, maxPoint vector3
+	first := true
+	for i := range shapes {
+		for _, point := range shapes[i].Points {
+			for k := 0; k < 3; k++ {
+				if first || point[k] < minPoint[k] {
+					minPoint[k] = point[k]
+				}
+				if first || point[k] > maxPoint[k] {
+					maxPoint[k] = point[k]
+				}
+			}
+			first = false
+		}
+	}
+	center := minPoint.add(maxPoint).scale(0.5)
+	radius := math.Max(maxPoint.add(minPoint.scale(-1)).length()/2, 1e-9)
+	distance := props.CameraDistance * radius
+	sinA, cosA := math.Sincos(props.Azimuth * math.Pi / 180)
+	sinE, cosE := math.Sincos(props.Elevation * math.Pi / 180)
+	projected := make([]projectedShape, len(shapes))
+	for i := range shapes {
+		projected[i].Shape = &shapes[i]
+		projected[i].Points = make([]helpers.Point, len(shapes[i].Points))
+		scaleSum := 0.0
+		for j, point := range shapes[i].Points {
+			p := point.add(center.scale(-1))
+			// turns around the vertical axis and then tilts towards the camera
+			x := p[0]*cosA + p[2]*sinA
+			z := -p[0]*sinA + p[2]*cosA
+			y := p[1]*cosE - z*sinE
+			z = p[1]*sinE + z*cosE
+			scale := 1.0
+			if props.Projection == LSYSTEM_PROJECTION_PERSPECTIVE {
+				scale = distance / (distance - z)
+			}
+			projected[i].Points[j] = helpers.Point{X: x * scale, Y: -y * scale}
+			projected[i].Z += z / float64(len(shapes[i].Points))
+			scaleSum += scale
+		}
+		projected[i].Scale = scaleSum / float64(len(shapes[i].Points))
+	}
+	return projected
+}
+
+// Draws the Lindenmayer system in space, projected, fitted to the image and
+// sorted from back to front.
+func (props *LindenmayerSystem) renderSpace(gc draw2d.GraphicContext) error {
+	shapes, measured, err := props.buildShapes()
+	if err != nil {
+		return err
+	}
+	projected := props.projectShapes(shapes)
+	bounds := lindenmayerBounds{Empty: true}
+	for _, shape := range projected {
+		for _, point := range shape.Points {
+			bounds.add(point.X, point.Y, shape.Shape.Width*shape.Scale)
+		}
+	}
+	if bounds.Empty {
+		return nil
+	}
+	view := props.fitView(bounds)
+	sort.SliceStable(projected, func(a, b int) bool {
+		return projected[a].Z < projected[b].Z
+	})
+	solidColor := props.Color
+	if props.UseRandomColors {
+		solidColor = helpers.RandomColor()
+	}
+	gc.SetLineCap(draw2d.RoundCap)
+	gc.SetLineJoin(draw2d.RoundJoin)
+	for _, shape := range projected {
+		lineColor := props.getColor(solidColor, shape.Shape.ColorIndex, shape.Shape.Depth, shape.Shape.PathLength, measured)
+		width := shape.Shape.Width * shape.Scale
+		gc.BeginPath()
+		switch {
+		case shape.Shape.IsDot:
+			x, y := view.apply(shape.Points[0].X, shape.Points[0].Y)
+			draw2dkit.Circle(gc, x, y, width/2)
+			gc.Close()
+			gc.SetFillColor(lineColor)
+			gc.Fill()
+		case len(shape.Points) == 2:
+			gc.MoveTo(view.apply(shape.Points[0].X, shape.Points[0].Y))
+			gc.LineTo(view.apply(shape.Points[1].X, shape.Points[1].Y))
+			gc.SetLineWidth(width)
+			gc.SetStrokeColor(lineColor)
+			gc.Stroke()
+		default:
+			gc.MoveTo(view.apply(shape.Points[0].X, shape.Points[0].Y))
+			for _, point := range shape.Points[1:] {
+				gc.LineTo(view.apply(point.X, point.Y))
+			}
+			gc.Close()
+			fillColor := lineColor
+			if props.UseFillColor {
+				fillColor = props.FillColor
+			}
+			gc.SetLineWidth(width)
+			gc.SetStrokeColor(lineColor)
+			gc.SetFillColor(fillColor)
+			gc.FillStroke()
+		}
+	}
+	return nil
+}
+
+// Writes the geometry of the Lindenmayer system in space to the given output
+// as a Wavefront OBJ file. A line mesh has the lines as line elements and
+// the dots as point elements, while a tube mesh has tubes around the lines
+// with the line width as their diameter and octahedra for the dots. The
+// polygons are faces in both.
+func (props *LindenmayerSystem) WriteOBJ(output io.Writer, mesh string) error {
+	shapes, _, err := props.buildShapes()
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(output)
+	vertexCount := 0
+	addVertex := func(v vector3) int {
+		fmt.Fprintf(writer, "v %g %g %g\n", v[0], v[1], v[2])
+		vertexCount++
+		return vertexCount
+	}
+	fmt.Fprintf(writer, "# Lindenmayer system: %d shapes\no l-system\n", len(shapes))
+	for _, shape := range shapes {
+		switch {
+		case shape.IsDot && mesh == LSYSTEM_MESH_TUBE:
+			writeOBJOctahedron(writer, shape.Points[0], shape.Width/2, addVertex)
+		case shape.IsDot:
+			fmt.Fprintf(writer, "p %d\n", addVertex(shape.Points[0]))
+		case len(shape.Points) == 2 && mesh == LSYSTEM_MESH_TUBE:
+			writeOBJTube(writer, shape.Points[0], shape.Points[1], shape.Width/2, addVertex)
+		case len(shape.Points) == 2:
+			a, b := addVertex(shape.Points[0]), addVertex(shape.Points[1])
+			fmt.Fprintf(writer, "l %d %d\n", a, b)
+		default:
+			indices := make([]int, len(shape.Points))
+			for i, point := range shape.Points {
+				indices[i] = addVertex(point)
+			}
+			writeOBJFace(writer, indices...)
+		}
+	}
+	return writer.Flush()
+}
+
+func writeOBJFace(writer io.Writer, indices ...int) {
+	fmt.Fprint(writer, "f")
+	for _, index := range indices {
+		fmt.Fprintf(writer, " %d", index)
+	}
+	fmt.Fprintln(writer)
+}
+
+// Writes a closed tube of the given radius from a to b, or a line if the tube
+// has no volume.
+func writeOBJTube(writer io.Writer, a, b vector3, radius float64, addVertex func(v vector3) int) {
+	axis := b.add(a.scale(-1))
+	if radius <= 0 || axis.length() == 0 {
+		start, end := addVertex(a), addVertex(b)
+		fmt.Fprintf(writer, "l %d %d\n", start, end)
+		return
+	}
+	axis = axis.scale(1 / axis.length())
+	normal := axis.cross(vector3{0, 0, 1})
+	if normal.length() < 1e-6 {
+		normal = axis.cross(vector3{1, 0, 0})
+	}
+	normal = normal.scale(1 / normal.length())
+	binormal := axis.cross(normal)
+	var bottom, top [LSYSTEM_TUBE_SIDES]int
+	for k := 0; k < LSYSTEM_TUBE_SIDES; k++ {
+		sin, cos := math.Sincos(2 * math.Pi * float64(k) / LSYSTEM_TUBE_SIDES)
+		offset := normal.scale(radius * cos).add(binormal.scale(radius * sin))
+		bottom[k] = addVertex(a.add(offset))
+		top[k] = addVertex(b.add(offset))
+	}
+	for k := 0; k < LSYSTEM_TUBE_SIDES; k++ {
+		next := (k + 1) % LSYSTEM_TUBE_SIDES
+		writeOBJFace(writer, bottom[k], bottom[next], top[next], top[k])
+	}
+	bottomCap := make([]int, LSYSTEM_TUBE_SIDES)
+	for k := range bottomCap {
+		bottomCap[k] = bottom[LSYSTEM_TUBE_SIDES-1-k]
+	}
+	writeOBJFace(writer, bottomCap...)
+	writeOBJFace(writer, top[:]...)
+}
+
+// Writes an octahedron of the given radius around a center, or a point if
+// the radius isn't positive.
+func writeOBJOctahedron(writer io.Writer, center vector3, radius float64, addVertex func(v vector3) int) {
+	if radius <= 0 {
+		fmt.Fprintf(writer, "p %d\n", addVertex(center))
+		return
+	}
+	var vertices [6]int
+	for k := 0; k < 3; k++ {
+		var offset vector3
+		offset[k] = radius
+		vertices[2*k] = addVertex(center.add(offset))
+		vertices[2*k+1] = addVertex(center.add(offset.scale(-1)))
+	}
+	for _, x := range []int{0, 1} {
+		for _, y := range []int{2, 3} {
+			for _, z := range []int{4, 5} {
+				if (x+y+z)%2 == 0 {
+					writeOBJFace(writer, vertices[x], vertices[y], vertices[z])
+				} else {
+					writeOBJFace(writer, vertices[x], vertices[z], vertices[y])
+				}
+			}
+		}
+	}
+}
+
+// Checks if a turtle mode is supported by the Lindenmayer system.
+func IsValidLSystemMode(txt string) bool {
+	return txt == LSYSTEM_MODE_2D || txt == LSYSTEM_MODE_3D
+}
+
+// Checks if a camera projection is supported by the Lindenmayer system.
+func IsValidLSystemProjection(txt string) bool {
+	return txt == LSYSTEM_PROJECTION_ORTHOGRAPHIC || txt == LSYSTEM_PROJECTION_PERSPECTIVE
+}
+
+// Checks if an OBJ mesh is supported by the Lindenmayer system.
+func IsValidLSystemMesh(txt string) bool {
+	return txt == LSYSTEM_MESH_LINE || txt == LSYSTEM_MESH_TUBE
+}
@@ -0,0 +1,72 @@
+package fractals
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/B3zaleel/fractage/src/helpers"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// The catalogue of the Lindenmayer systems that can be picked by name.
+	LSYSTEM_PRESETS_FILE = "src/data/lsystems.yaml"
+)
+
+// Represents a Lindenmayer system of the preset catalogue.
+type LSystemPreset struct {
+	Name        string `yaml:"name" json:"name"`
+	Description string `yaml:"description" json:"description"`
+	Axiom       string `yaml:"axiom" json:"axiom"`
+	// The rewrite rules in the syntax of ParseLindenmayerRules, one per item.
+	Rules        []string `yaml:"rules" json:"rules"`
+	TurningAngle float64  `yaml:"turning_angle" json:"turning_angle"`
+	Angle        float64  `yaml:"angle" json:"angle"`
+	DrawSymbols  string   `yaml:"draw_symbols" json:"draw_symbols"`
+	SkipSymbols  string   `yaml:"skip_symbols" json:"skip_symbols"`
+	// The suggested number of iterations.
+	Iterations    int    `yaml:"iterations" json:"iterations"`
+	IgnoreSymbols string `yaml:"ignore" json:"ignore"`
+	// The turtle mode, which is 2d when it's empty.
+	Mode string `yaml:"mode" json:"mode"`
+}
+
+// Loads the Lindenmayer systems of the preset catalogue.
+func LoadLSystemPresets() ([]LSystemPreset, error) {
+	var presets []LSystemPreset
+	file, err := os.ReadFile(LSYSTEM_PRESETS_FILE)
+	if err != nil {
+		return nil, err
+	}
+	err = yaml.Unmarshal(file, &presets)
+	if err != nil {
+		return nil, err
+	}
+	for i := range presets {
+		if presets[i].Mode == "" {
+			presets[i].Mode = LSYSTEM_MODE_2D
+		}
+	}
+	return presets, nil
+}
+
+// Finds the preset with the given name, ignoring case.
+func FindLSystemPreset(name string) (LSystemPreset, error) {
+	presets, err := LoadLSystemPresets()
+	if err != nil {
+		return LSystemPreset{}, err
+	}
+	name = strings.Trim(name, helpers.WHITESPACE_CUTSET)
+	for _, preset := range presets {
+		if strings.EqualFold(preset.Name, name) {
+			return preset, nil
+		}
+	}
+	return LSystemPreset{}, fmt.Errorf("L-system preset not found: %s", name)
+}
+
+// Retrieves the rewrite rules of this preset as a comma-separated list.
+func (preset *LSystemPreset) RulesText() string {
+	return strings.Join(preset.Rules, ",")
+}
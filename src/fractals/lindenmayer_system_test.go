@@ -0,0 +1,44 @@
+package fractals
+
+import (
+	"testing"
+)
+
+// Retrieves the symbols of the successor of the only rule of a symbol.
+func successorSymbols(t *testing.T, rules map[rune][]LindenmayerRule, symbol rune) string {
+	t.Helper()
+	if len(rules[symbol]) != 1 || len(rules[symbol][0].Successors) != 1 {
+		t.Fatalf("got rules %v for %c, want one rule with one successor", rules[symbol], symbol)
+	}
+	var symbols []rune
+	for _, module := range rules[symbol][0].Successors[0].Modules {
+		symbols = append(symbols, module.Symbol)
+	}
+	return string(symbols)
+}
+
+func TestParseLindenmayerRulesQuotes(t *testing.T) {
+	tests := []struct {
+		txt  string
+		want map[rune]string
+	}{
+		{`F=FF,X=F+X`, map[rune]string{'F': "FF", 'X': "F+X"}},
+		{`"F=F,F", X=F+X`, map[rune]string{'F': "F,F", 'X': "F+X"}},
+		{`A(l,w) -> F(l)[+(30)A(l*0.7,w)]`, map[rune]string{'A': "F[+A]"}},
+	}
+	for _, test := range tests {
+		rules, err := ParseLindenmayerRules(test.txt)
+		if err != nil {
+			t.Errorf("%s: %s", test.txt, err)
+			continue
+		}
+		for symbol, want := range test.want {
+			if got := successorSymbols(t, rules, symbol); got != want {
+				t.Errorf("%s: got successor %q for %c, want %q", test.txt, got, symbol, want)
+			}
+		}
+	}
+	if _, err := ParseLindenmayerRules(`"F=F,F`); err == nil {
+		t.Error("got no error for an unclosed double quote")
+	}
+}
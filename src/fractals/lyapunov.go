@@ -0,0 +1,143 @@
+package fractals
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strings"
+
+	"github.com/B3zaleel/fractage/src/helpers"
+)
+
+const (
+	LYAPUNOV_MAX_ITERATIONS                = 100_000
+	LYAPUNOV_DEFAULT_ITERATIONS            = 200
+	LYAPUNOV_DEFAULT_WARM_UP_ITERATIONS    = 50
+	LYAPUNOV_DEFAULT_SEQUENCE              = "AB"
+	LYAPUNOV_DEFAULT_REGION                = "2, 2, 2, 2"
+	LYAPUNOV_DEFAULT_X0                    = 0.5
+	LYAPUNOV_DEFAULT_STABLE_COLOR_PALETTE  = "fire"
+	LYAPUNOV_DEFAULT_CHAOTIC_COLOR_PALETTE = "monochrome"
+)
+
+// Properties of a Lyapunov fractal image.
+type Lyapunov struct {
+	Width               int
+	Height              int
+	Sequence            string
+	Region              helpers.Rect
+	WarmUpIterations    int
+	Iterations          int
+	X0                  float64
+	StableColorPalette  helpers.ColorPalette
+	ChaoticColorPalette helpers.ColorPalette
+	Background          color.RGBA
+	// The number of bits per channel of the image, which is 8 or 16.
+	BitDepth int
+}
+
+// Creates the Lyapunov fractal image.
+func (props *Lyapunov) CreateImage() (image.Image, error) {
+	viewport := image.Rect(0, 0, props.Width, props.Height)
+	img := helpers.NewImage(viewport, props.BitDepth)
+	helpers.FillImage(img, props.Background)
+	err := props.render(img)
+	if err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+// Helper function for rendering the Lyapunov fractal.
+func (props *Lyapunov) render(img draw.RGBA64Image) error {
+	width, height := float64(props.Width), float64(props.Height)
+	step := math.Max(props.Region.Width/width, props.Region.Height/height)
+	xOffset := props.Region.X - (width*step-props.Region.Width)/2.0
+	yOffset := props.Region.Y - (height*step-props.Region.Height)/2.0
+	err := props.StableColorPalette.TranslateColorTransitions()
+	if err != nil {
+		return err
+	}
+	err = props.ChaoticColorPalette.TranslateColorTransitions()
+	if err != nil {
+		return err
+	}
+	// useB[i] is true if the ith rate of the sequence is b
+	sequence := []rune(strings.ToUpper(props.Sequence))
+	useB := make([]bool, len(sequence))
+	for i, c := range sequence {
+		useB[i] = c == 'B'
+	}
+	var pixelColor color.RGBA64
+	for y := 0; y < int(height); y++ {
+		for x := 0; x < int(width); x++ {
+			a := xOffset + float64(x)*step
+			b := yOffset + float64(y)*step
+			exponent := props.exponent(a, b, useB)
+			if math.IsNaN(exponent) {
+				continue
+			}
+			if exponent < 0 {
+				pixelColor, err = props.StableColorPalette.GetColor(1 - math.Exp(exponent))
+			} else {
+				pixelColor, err = props.ChaoticColorPalette.GetColor(1 - math.Exp(-exponent))
+			}
+			if err != nil {
+				return err
+			}
+			img.SetRGBA64(x, y, pixelColor)
+		}
+	}
+	return nil
+}
+
+// Computes the Lyapunov exponent of the logistic map x = r * x * (1 - x),
+// where r is driven by the sequence of the rates a and b.
+func (props *Lyapunov) exponent(a, b float64, useB []bool) float64 {
+	x := props.X0
+	r := a
+	n := 0
+	for i := 0; i < props.WarmUpIterations; i++ {
+		r = a
+		if useB[n] {
+			r = b
+		}
+		x = r * x * (1 - x)
+		n = (n + 1) % len(useB)
+	}
+	sum := 0.0
+	terms := 0
+	for i := 0; i < props.Iterations; i++ {
+		r = a
+		if useB[n] {
+			r = b
+		}
+		// zero derivatives, such as the one at x = 0.5, are skipped since
+		// a single one would make the exponent -Inf
+		derivative := math.Abs(r * (1 - 2*x))
+		if derivative > 0 {
+			sum += math.Log(derivative)
+			terms++
+		}
+		x = r * x * (1 - x)
+		n = (n + 1) % len(useB)
+	}
+	if terms == 0 {
+		return math.Inf(-1)
+	}
+	return sum / float64(terms)
+}
+
+// Checks if a sequence consists of only the rates A and B.
+func IsValidLyapunovSequence(txt string) bool {
+	if len(txt) == 0 {
+		return false
+	}
+	for _, c := range strings.ToUpper(txt) {
+		if c != 'A' && c != 'B' {
+			return false
+		}
+	}
+	return true
+}
@@ -3,8 +3,7 @@ package fractals
 import (
 	"image"
 	"image/color"
-	"image/png"
-	"io"
+	"image/draw"
 	"math"
 	"math/cmplx"
 
@@ -21,26 +20,34 @@ type MandelbrotSet struct {
 	BailOut       float64
 	Region        helpers.Rect
 	Background    color.RGBA
+	// The number of bits per channel of the image, which is 8 or 16.
+	BitDepth int
 }
 
-// Writes the Mandelbrot set image to the given output.
-func (props *MandelbrotSet) WriteImage(output io.Writer) error {
+// Creates the Mandelbrot set image.
+func (props *MandelbrotSet) CreateImage() (image.Image, error) {
 	viewport := image.Rect(0, 0, props.Width, props.Height)
-	img := image.NewRGBA(viewport)
+	img := helpers.NewImage(viewport, props.BitDepth)
 	helpers.FillImage(img, props.Background)
 	err := props.render(img)
 	if err != nil {
-		return err
-	}
-	err = png.Encode(output, img)
-	if err != nil {
-		return err
+		return nil, err
 	}
-	return nil
+	return img, nil
+}
+
+// Creates a copy of this Mandelbrot set that renders the given region with the
+// given number of iterations.
+func (props *MandelbrotSet) ZoomFrame(region helpers.Rect, iterations int) ZoomFractal {
+	frame := *props
+	frame.Region = region
+	frame.MaxIterations = iterations
+	frame.ColorPalette = props.ColorPalette.Copy()
+	return &frame
 }
 
 // Helper function for rendering the Mandelbrot set.
-func (props *MandelbrotSet) render(img *image.RGBA) error {
+func (props *MandelbrotSet) render(img draw.RGBA64Image) error {
 	width, height := float64(props.Width), float64(props.Height)
 	bailOutPow := math.Pow(props.BailOut, props.M)
 	step := math.Max(props.Region.Width/width, props.Region.Height/height)
@@ -50,7 +57,7 @@ func (props *MandelbrotSet) render(img *image.RGBA) error {
 	if err != nil {
 		return err
 	}
-	var pixelColor color.RGBA
+	var pixelColor color.RGBA64
 	var x2, y2 float64
 	var C, Z complex128
 	var n int
@@ -81,7 +88,7 @@ func (props *MandelbrotSet) render(img *image.RGBA) error {
 					return err
 				}
 			}
-			img.Set(x, y, pixelColor)
+			img.SetRGBA64(x, y, pixelColor)
 		}
 	}
 	return nil
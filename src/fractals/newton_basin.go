@@ -1,19 +1,73 @@
 package fractals
 
 import (
+	"fmt"
 	"image"
 	"image/color"
-	"image/png"
-	"io"
+	"image/draw"
 	"math"
 	"math/cmplx"
+	"strings"
 
 	"github.com/B3zaleel/fractage/src/helpers"
 	math_helpers "github.com/B3zaleel/fractage/src/helpers/math"
+	"github.com/llgcode/draw2d"
 )
 
 const (
-	MAX_DELTA = 1e-14
+	MAX_DELTA                               = 1e-14
+	NEWTON_BASIN_DEFAULT_ROOT_TOLERANCE     = 1e-6
+	NEWTON_BASIN_DEFAULT_ROOT_MARKER_RADIUS = 4
+	NEWTON_BASIN_DEFAULT_METHOD             = "newton"
+	NEWTON_BASIN_DEFAULT_ORDER              = 3
+	NEWTON_BASIN_MAX_ORDER                  = 10
+	NEWTON_BASIN_DEFAULT_RELAXATION         = 1 + 0i
+	NEWTON_BASIN_DEFAULT_Z0                 = 1 + 0i
+	NEWTON_BASIN_NOVA_MODE_JULIA            = "julia"
+	NEWTON_BASIN_NOVA_MODE_PARAMETER        = "parameter"
+	NEWTON_BASIN_DERIVATIVE_SYMBOLIC        = "symbolic"
+	NEWTON_BASIN_DERIVATIVE_NUMERIC         = "numeric"
+	// The number of roots of a function that can be found while rendering.
+	NEWTON_BASIN_MAX_DISCOVERED_ROOTS = 64
+	// The conjugate of the golden ratio, which spreads the colors of
+	// roots that are found while rendering across the color palette.
+	GOLDEN_RATIO_CONJUGATE = 0.6180339887498949
+)
+
+var (
+	// The root-finding methods, each of which computes the step taken from z
+	// before it is scaled by the relaxation factor.
+	NEWTON_BASIN_METHODS = map[string]func(*NewtonBasin) func(complex128) complex128{
+		"newton": func(props *NewtonBasin) func(complex128) complex128 {
+			f, f1 := props.derivatives[0], props.derivatives[1]
+			return func(z complex128) complex128 {
+				return -f(z) / f1(z)
+			}
+		},
+		"halley": func(props *NewtonBasin) func(complex128) complex128 {
+			f, f1, f2 := props.derivatives[0], props.derivatives[1], props.derivatives[2]
+			return func(z complex128) complex128 {
+				fz, f1z := f(z), f1(z)
+				return -2 * fz * f1z / (2*f1z*f1z - fz*f2(z))
+			}
+		},
+		"householder": func(props *NewtonBasin) func(complex128) complex128 {
+			return func(z complex128) complex128 { return householderStep(props, z) }
+		},
+		"schroder": func(props *NewtonBasin) func(complex128) complex128 {
+			f, f1, f2 := props.derivatives[0], props.derivatives[1], props.derivatives[2]
+			return func(z complex128) complex128 {
+				fz, f1z := f(z), f1(z)
+				return -fz * f1z / (f1z*f1z - fz*f2(z))
+			}
+		},
+		"nova": func(props *NewtonBasin) func(complex128) complex128 {
+			f, f1 := props.derivatives[0], props.derivatives[1]
+			return func(z complex128) complex128 {
+				return -f(z) / f1(z)
+			}
+		},
+	}
 )
 
 // Properties of a Newton basin image.
@@ -23,30 +77,54 @@ type NewtonBasin struct {
 	ColorPalette     helpers.ColorPalette
 	MaxIterations    int
 	Polynomial       math_helpers.CmplxPolynomial
+	Expression       *math_helpers.CmplxExpression
+	DerivativeMode   string
 	BailOut          float64
 	Region           helpers.Rect
 	Background       color.RGBA
+	BitDepth         int
 	UseDynamicColors bool
+	Roots            []complex128
+	UseRootColors    bool
+	RootTolerance    float64
+	MarkRoots        bool
+	RootMarkerColor  color.RGBA
+	RootMarkerRadius float64
+	Method           string
+	Order            int
+	Relaxation       complex128
+	NovaMode         string
+	C                complex128
+	Z0               complex128
+	derivatives      []func(complex128) complex128
+	discoverRoots    bool
 }
 
-// Writes the Newton basin image to the given output.
-func (props *NewtonBasin) WriteImage(output io.Writer) error {
+// Creates the Newton basin image.
+func (props *NewtonBasin) CreateImage() (image.Image, error) {
 	viewport := image.Rect(0, 0, props.Width, props.Height)
-	img := image.NewRGBA(viewport)
+	img := helpers.NewImage(viewport, props.BitDepth)
 	helpers.FillImage(img, props.Background)
 	err := props.render(img)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	err = png.Encode(output, img)
-	if err != nil {
-		return err
-	}
-	return nil
+	return img, nil
+}
+
+// Creates a copy of this Newton basin that renders the given region with the
+// given number of iterations.
+func (props *NewtonBasin) ZoomFrame(region helpers.Rect, iterations int) ZoomFractal {
+	frame := *props
+	frame.Region = region
+	frame.MaxIterations = iterations
+	frame.ColorPalette = props.ColorPalette.Copy()
+	frame.Roots = append([]complex128(nil), props.Roots...)
+	return &frame
 }
 
 // Helper function for rendering the Newton basin.
-func (props *NewtonBasin) render(img *image.RGBA) error {
+func (props *NewtonBasin) render(img draw.RGBA64Image) error {
 	width, height := float64(props.Width), float64(props.Height)
 	step := math.Max(props.Region.Width/width, props.Region.Height/height)
 	xOffset := props.Region.X - (width*step-props.Region.Width)/2.0
@@ -55,41 +133,220 @@ func (props *NewtonBasin) render(img *image.RGBA) error {
 	if err != nil {
 		return err
 	}
-	var pixelColor color.RGBA
+	err = props.buildDerivatives()
+	if err != nil {
+		return err
+	}
+	isNova := props.Method == "nova"
+	// the roots are points of the z-plane, which the parameter mode of the
+	// Nova method doesn't draw, and they're only the fixed points of the Nova
+	// method when c is 0
+	markRoots := props.MarkRoots && !(isNova && (props.NovaMode == NEWTON_BASIN_NOVA_MODE_PARAMETER || props.C != 0))
+	if (props.UseRootColors || markRoots) && len(props.Roots) == 0 {
+		if props.Expression != nil {
+			// the roots of an expression are found while rendering
+			props.discoverRoots = true
+		} else {
+			props.Roots, err = props.Polynomial.Roots(math_helpers.ROOTS_DEFAULT_MAX_ITERATIONS, math_helpers.ROOTS_DEFAULT_TOLERANCE)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	method, ok := NEWTON_BASIN_METHODS[props.Method]
+	if !ok {
+		return fmt.Errorf("Unknown method: %s", props.Method)
+	}
+	methodStep := method(props)
+	var pixelColor color.RGBA64
 	var n int
-	poly := props.Polynomial
-	polyDeriv := props.Polynomial.FirstDerivative()
+	var C complex128
 	for y := 0; y <= int(height); y++ {
 		for x := 0; x <= int(width); x++ {
 			n = 0
 			Z := complex(xOffset+float64(x)*step, yOffset+float64(y)*step)
+			C = 0
+			if isNova {
+				C = props.C
+				if props.NovaMode == NEWTON_BASIN_NOVA_MODE_PARAMETER {
+					C = Z
+					Z = props.Z0
+				}
+			}
 			delta := Z
 			Z1 := Z
 			for (n < props.MaxIterations) && (cmplx.Abs(Z) < props.BailOut) && (cmplx.Abs(delta) > MAX_DELTA) {
-				Z = Z - poly.Evaluate(Z)/polyDeriv.Evaluate(Z)
+				Z = Z + props.Relaxation*methodStep(Z) + C
 				delta = Z1 - Z
 				Z1 = Z
 				n++
 			}
 			mag := float64(props.MaxIterations-n) / float64(props.MaxIterations)
-			if props.UseDynamicColors {
+			rootIndex := -1
+			if props.UseRootColors || props.discoverRoots {
+				rootIndex = props.findRoot(Z)
+			}
+			if props.UseRootColors {
+				if rootIndex < 0 {
+					continue
+				}
+				pixelColor, err = props.rootColor(rootIndex, mag)
+				if err != nil {
+					return err
+				}
+			} else if props.UseDynamicColors {
 				var angle float64
 				if Z == 0+0i {
 					angle = 0
 				} else {
 					angle = cmplx.Phase(Z)
 				}
-				pixelColor = color.RGBA{
-					R: uint8(255 * mag * (math.Sin(angle)/2 + 0.5)),
-					G: uint8(255 * mag * (math.Sin(angle+1*math.Pi/3)/2 + 0.5)),
-					B: uint8(255 * mag * (math.Sin(angle+5*math.Pi/3)/2 + 0.5)),
-					A: 255,
+				pixelColor = color.RGBA64{
+					R: uint16(0xffff * mag * (math.Sin(angle)/2 + 0.5)),
+					G: uint16(0xffff * mag * (math.Sin(angle+1*math.Pi/3)/2 + 0.5)),
+					B: uint16(0xffff * mag * (math.Sin(angle+5*math.Pi/3)/2 + 0.5)),
+					A: 0xffff,
 				}
 			} else {
 				pixelColor, err = props.ColorPalette.GetColor(mag)
 			}
-			img.Set(x, y, pixelColor)
+			img.SetRGBA64(x, y, pixelColor)
 		}
 	}
+	if markRoots {
+		helpers.DrawOver(img, func(gc draw2d.GraphicContext) {
+			for _, root := range props.Roots {
+				rootX := (real(root) - xOffset) / step
+				rootY := (imag(root) - yOffset) / step
+				helpers.DrawFilledCircle(gc, rootX, rootY, props.RootMarkerRadius, props.RootMarkerColor, props.RootMarkerColor)
+			}
+		})
+	}
 	return nil
 }
+
+// Builds the functions that evaluate the function whose roots are found
+// and the derivatives needed by the root-finding method.
+func (props *NewtonBasin) buildDerivatives() error {
+	count := 3
+	if props.Method == "householder" && props.Order+1 > count {
+		count = props.Order + 1
+	}
+	props.derivatives = make([]func(complex128) complex128, count)
+	if props.Expression == nil {
+		derivative := props.Polynomial
+		for i := 0; i < count; i++ {
+			polynomial := derivative
+			props.derivatives[i] = polynomial.Evaluate
+			derivative = derivative.FirstDerivative()
+		}
+		return nil
+	}
+	expression := props.Expression
+	props.derivatives[0] = expression.Evaluate
+	for i := 1; i < count; i++ {
+		if props.DerivativeMode == NEWTON_BASIN_DERIVATIVE_NUMERIC {
+			props.derivatives[i] = math_helpers.NumericDerivative(props.derivatives[i-1])
+			continue
+		}
+		derivative, err := expression.FirstDerivative()
+		if err != nil {
+			return err
+		}
+		expression = &derivative
+		props.derivatives[i] = expression.Evaluate
+	}
+	return nil
+}
+
+// Computes the step of Householder's method of the configured order, which
+// is d * (1/f)^(d-1) / (1/f)^(d) for an order d.
+func householderStep(props *NewtonBasin, z complex128) complex128 {
+	order := props.Order
+	fk := make([]complex128, order+1)
+	for k := 0; k <= order; k++ {
+		fk[k] = props.derivatives[k](z)
+	}
+	if fk[0] == 0 {
+		// z is a root
+		return 0
+	}
+	// gk holds the derivatives of g = 1/f, which follow from the
+	// derivatives of f * g = 1.
+	gk := make([]complex128, order+1)
+	gk[0] = 1 / fk[0]
+	binomial := make([]float64, order+1)
+	binomial[0] = 1
+	for n := 1; n <= order; n++ {
+		for k := n; k > 0; k-- {
+			binomial[k] += binomial[k-1]
+		}
+		sum := 0.0 + 0i
+		for k := 1; k <= n; k++ {
+			sum += complex(binomial[k], 0) * fk[k] * gk[n-k]
+		}
+		gk[n] = -sum / fk[0]
+	}
+	return complex(float64(order), 0) * gk[order-1] / gk[order]
+}
+
+// Checks if a method name exists in the set of NEWTON_BASIN_METHODS names.
+func IsValidNewtonBasinMethod(txt string) bool {
+	methodName := strings.Trim(txt, helpers.WHITESPACE_CUTSET)
+	for name := range NEWTON_BASIN_METHODS {
+		if name == methodName {
+			return true
+		}
+	}
+	return false
+}
+
+// Retrieves the index of the root that is closest to z or -1 if z isn't
+// within the root tolerance of any root.
+func (props *NewtonBasin) findRoot(z complex128) int {
+	rootIndex := -1
+	minDistance := props.RootTolerance
+	for i, root := range props.Roots {
+		distance := cmplx.Abs(z - root)
+		if distance < minDistance {
+			minDistance = distance
+			rootIndex = i
+		}
+	}
+	if rootIndex < 0 && props.discoverRoots && len(props.Roots) < NEWTON_BASIN_MAX_DISCOVERED_ROOTS && cmplx.Abs(props.derivatives[0](z)) < props.RootTolerance {
+		props.Roots = append(props.Roots, z)
+		rootIndex = len(props.Roots) - 1
+	}
+	return rootIndex
+}
+
+// Computes the color of a pixel in the basin of the root with the given
+// index, shaded by the speed of convergence.
+func (props *NewtonBasin) rootColor(rootIndex int, mag float64) (color.RGBA64, error) {
+	var baseColor color.RGBA64
+	pos := float64(rootIndex) / float64(len(props.Roots))
+	if props.discoverRoots {
+		pos = math.Mod(float64(rootIndex)*GOLDEN_RATIO_CONJUGATE, 1)
+	}
+	if props.UseDynamicColors {
+		angle := 2 * math.Pi * pos
+		baseColor = color.RGBA64{
+			R: uint16(0xffff * (math.Sin(angle)/2 + 0.5)),
+			G: uint16(0xffff * (math.Sin(angle+2*math.Pi/3)/2 + 0.5)),
+			B: uint16(0xffff * (math.Sin(angle+4*math.Pi/3)/2 + 0.5)),
+			A: 0xffff,
+		}
+	} else {
+		var err error
+		baseColor, err = props.ColorPalette.GetColor(pos)
+		if err != nil {
+			return helpers.NIL_COLOR_64, err
+		}
+	}
+	return color.RGBA64{
+		R: uint16(float64(baseColor.R) * mag),
+		G: uint16(float64(baseColor.G) * mag),
+		B: uint16(float64(baseColor.B) * mag),
+		A: 0xffff,
+	}, nil
+}
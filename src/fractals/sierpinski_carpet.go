@@ -3,11 +3,11 @@ package fractals
 import (
 	"image"
 	"image/color"
-	"image/png"
 	"io"
 	"math"
 
 	"github.com/B3zaleel/fractage/src/helpers"
+	"github.com/llgcode/draw2d"
 	"github.com/llgcode/draw2d/draw2dimg"
 )
 
@@ -21,27 +21,44 @@ type SierpinskiCarpet struct {
 	Background      color.RGBA
 }
 
-// Writes the Sierpinski carpet image to the given output.
-func (props *SierpinskiCarpet) WriteImage(output io.Writer) {
+// Creates the Sierpinski carpet image.
+func (props *SierpinskiCarpet) CreateImage() (image.Image, error) {
 	viewport := image.Rect(0, 0, props.Width, props.Height)
 	img := image.NewRGBA(viewport)
 	gc := draw2dimg.NewGraphicContext(img)
+	helpers.FillImage(img, props.Background)
+	props.draw(gc)
+	return img, nil
+}
+
+// Writes the Sierpinski carpet as a PDF document to the given output, whose
+// page has the size of the image at the resolution of the options.
+func (props *SierpinskiCarpet) WritePDF(output io.Writer, options helpers.PdfOptions) error {
+	pdf, gc := helpers.NewPdfGraphicContext(props.Width, props.Height, props.Background, options)
+	props.draw(gc)
+	return helpers.WritePdf(output, pdf)
+}
+
+// Writes the Sierpinski carpet as an SVG document to the given output.
+func (props *SierpinskiCarpet) WriteSVG(output io.Writer) error {
+	svg, gc := helpers.NewSvgGraphicContext(props.Width, props.Height, props.Background)
+	props.draw(gc)
+	return helpers.WriteSvg(output, svg)
+}
+
+// Draws the Sierpinski carpet in the middle of the image.
+func (props *SierpinskiCarpet) draw(gc draw2d.GraphicContext) {
 	minSide := math.Min(float64(props.Width), float64(props.Height))
 	x1 := 0 + float64(props.Width)/2 - minSide/2
 	x2 := x1 + minSide
 	y1 := 0 + float64(props.Height)/2 - minSide/2
 	y2 := y1 + minSide
-	helpers.FillImage(img, props.Background)
 	helpers.DrawRectangle(gc, x1, y1, x2-x1, y2-y1, color.RGBA{0, 0, 0, 255})
 	props.render(gc, x1, y1, x2, y2, props.Iterations)
-	err := png.Encode(output, img)
-	if err != nil {
-		panic(err)
-	}
 }
 
 // Helper function for rendering the Sierpinski carpet.
-func (props *SierpinskiCarpet) render(gc *draw2dimg.GraphicContext, x1, y1, x2, y2 float64, level int) {
+func (props *SierpinskiCarpet) render(gc draw2d.GraphicContext, x1, y1, x2, y2 float64, level int) {
 	if level > 0 {
 		x1n := 2*x1/3 + x2/3
 		x2n := x1/3 + 2*x2/3
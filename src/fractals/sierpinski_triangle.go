@@ -3,11 +3,11 @@ package fractals
 import (
 	"image"
 	"image/color"
-	"image/png"
 	"io"
 	"math"
 
 	"github.com/B3zaleel/fractage/src/helpers"
+	"github.com/llgcode/draw2d"
 	"github.com/llgcode/draw2d/draw2dimg"
 )
 
@@ -21,11 +21,33 @@ type SierpinskiTriangle struct {
 	Background      color.RGBA
 }
 
-// Writes the Sierpinski triangle image to the given output.
-func (props *SierpinskiTriangle) WriteImage(output io.Writer) {
+// Creates the Sierpinski triangle image.
+func (props *SierpinskiTriangle) CreateImage() (image.Image, error) {
 	viewport := image.Rect(0, 0, props.Width, props.Height)
 	img := image.NewRGBA(viewport)
 	gc := draw2dimg.NewGraphicContext(img)
+	helpers.FillImage(img, props.Background)
+	props.draw(gc)
+	return img, nil
+}
+
+// Writes the Sierpinski triangle as a PDF document to the given output, whose
+// page has the size of the image at the resolution of the options.
+func (props *SierpinskiTriangle) WritePDF(output io.Writer, options helpers.PdfOptions) error {
+	pdf, gc := helpers.NewPdfGraphicContext(props.Width, props.Height, props.Background, options)
+	props.draw(gc)
+	return helpers.WritePdf(output, pdf)
+}
+
+// Writes the Sierpinski triangle as an SVG document to the given output.
+func (props *SierpinskiTriangle) WriteSVG(output io.Writer) error {
+	svg, gc := helpers.NewSvgGraphicContext(props.Width, props.Height, props.Background)
+	props.draw(gc)
+	return helpers.WriteSvg(output, svg)
+}
+
+// Draws the Sierpinski triangle in the middle of the image.
+func (props *SierpinskiTriangle) draw(gc draw2d.GraphicContext) {
 	var side, height float64
 	if props.Width > props.Height {
 		height = float64(props.Height)
@@ -38,16 +60,11 @@ func (props *SierpinskiTriangle) WriteImage(output io.Writer) {
 	pt1 := helpers.Point{X: midX, Y: midY - height/2}
 	pt2 := helpers.Point{X: midX + side/2, Y: midY + height/2}
 	pt3 := helpers.Point{X: midX - side/2, Y: midY + height/2}
-	helpers.FillImage(img, props.Background)
 	props.render(gc, pt1, pt2, pt3, props.Iterations)
-	err := png.Encode(output, img)
-	if err != nil {
-		panic(err)
-	}
 }
 
 // Helper function for rendering the Sierpinski triangle.
-func (props *SierpinskiTriangle) render(gc *draw2dimg.GraphicContext, pt1, pt2, pt3 helpers.Point, level int) {
+func (props *SierpinskiTriangle) render(gc draw2d.GraphicContext, pt1, pt2, pt3 helpers.Point, level int) {
 	if level > 0 {
 		pt1New := helpers.Point{X: (pt1.X + pt2.X) / 2, Y: (pt1.Y + pt2.Y) / 2}
 		pt2New := helpers.Point{X: (pt2.X + pt3.X) / 2, Y: (pt2.Y + pt3.Y) / 2}
@@ -0,0 +1,107 @@
+package fractals
+
+import (
+	"image"
+	"math"
+
+	"github.com/B3zaleel/fractage/src/helpers"
+)
+
+const (
+	ZOOM_ANIMATION_DEFAULT_FRAMES = 30
+	ZOOM_ANIMATION_MAX_FRAMES     = 600
+	// The zoom factor per frame.
+	ZOOM_ANIMATION_DEFAULT_ZOOM = 1.1
+	// The fraction of the starting iterations added each time the region is
+	// halved.
+	ZOOM_ANIMATION_DEFAULT_ITERATION_GROWTH = 0.25
+	// The deepest zoom, beyond which the precision of float64 coordinates
+	// breaks the image into blocks.
+	ZOOM_ANIMATION_MAX_DEPTH = 1e13
+)
+
+// Represents a fractal of the complex plane whose region can be zoomed into.
+type ZoomFractal interface {
+	CreateImage() (image.Image, error)
+	// Creates a copy of this fractal that renders the given region with the
+	// given number of iterations.
+	ZoomFrame(region helpers.Rect, iterations int) ZoomFractal
+}
+
+// Properties of an animation that zooms into a fractal.
+type ZoomAnimation struct {
+	Fractal ZoomFractal
+	// The region of the first frame.
+	Region helpers.Rect
+	// The center of the region of the last frame.
+	Center helpers.Point
+	// The factor the region shrinks by in each frame.
+	Zoom       float64
+	Frames     int
+	Iterations int
+	// The most iterations a frame is rendered with.
+	MaxIterations int
+	// The fraction of the starting iterations added each time the region is
+	// halved.
+	IterationGrowth float64
+	// The number of frames rendered at the same time.
+	Workers int
+}
+
+// Computes how many times the region of the last frame is smaller than the
+// region of the first frame.
+func (props *ZoomAnimation) Depth() float64 {
+	return math.Pow(props.Zoom, float64(props.Frames-1))
+}
+
+// Computes the region of the frame with the given index. The center moves
+// from the center of the first region to the target center, keeping pace
+// with the zoom so that it arrives in the last frame.
+func (props *ZoomAnimation) FrameRegion(i int) helpers.Rect {
+	scale := math.Pow(props.Zoom, -float64(i))
+	lastScale := 1 / props.Depth()
+	t := 0.0
+	if props.Frames > 1 {
+		t = float64(i) / float64(props.Frames-1)
+		if lastScale != 1 {
+			t = (1 - scale) / (1 - lastScale)
+		}
+	}
+	startX := props.Region.X + props.Region.Width/2
+	startY := props.Region.Y + props.Region.Height/2
+	centerX := startX + t*(props.Center.X-startX)
+	centerY := startY + t*(props.Center.Y-startY)
+	width, height := props.Region.Width*scale, props.Region.Height*scale
+	return helpers.Rect{X: centerX - width/2, Y: centerY - height/2, Width: width, Height: height}
+}
+
+// Computes the number of iterations of the frame with the given index, which
+// grows with the log of the depth of the zoom up to the max iterations.
+func (props *ZoomAnimation) FrameIterations(i int) int {
+	depth := math.Max(0, float64(i)*math.Log2(props.Zoom))
+	iterations := math.Round(float64(props.Iterations) * (1 + props.IterationGrowth*depth))
+	return int(math.Min(iterations, float64(props.MaxIterations)))
+}
+
+// Creates the frames of the animation. The first frame is rendered before
+// the others, which are copies of it, so that what's found while rendering
+// it, such as the roots of a Newton basin, is shared by all the frames.
+func (props *ZoomAnimation) CreateFrames() ([]image.Image, error) {
+	frames := make([]image.Image, props.Frames)
+	first := props.Fractal.ZoomFrame(props.FrameRegion(0), props.FrameIterations(0))
+	img, err := first.CreateImage()
+	if err != nil {
+		return nil, err
+	}
+	frames[0] = img
+	err = helpers.ParallelFor(props.Frames-1, props.Workers, func(i int) error {
+		frame := first.ZoomFrame(props.FrameRegion(i+1), props.FrameIterations(i+1))
+		img, err := frame.CreateImage()
+		frames[i+1] = img
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return frames, nil
+}
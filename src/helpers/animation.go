@@ -0,0 +1,215 @@
+package helpers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"io"
+	"math"
+	"runtime"
+)
+
+const (
+	// The time each frame of an animation is shown for, in milliseconds.
+	ANIMATION_DEFAULT_DELAY = 100
+	ANIMATION_MAX_DELAY     = math.MaxUint16
+	// The denominator of the delays of APNG frames, which are in milliseconds.
+	APNG_DELAY_DENOMINATOR = 1000
+)
+
+var (
+	PNG_SIGNATURE = []byte("\x89PNG\r\n\x1a\n")
+)
+
+// Represents the frames of an animation and how they're played.
+type Animation struct {
+	Frames []image.Image
+	// The time each frame is shown for, in milliseconds.
+	Delay int
+	// The number of times the animation is played, where 0 plays it forever.
+	Loops int
+}
+
+// Represents a chunk of a PNG file.
+type pngChunk struct {
+	Type string
+	Data []byte
+}
+
+// Writes an animation to the given output in the format of the options.
+func EncodeAnimation(output io.Writer, animation Animation, options OutputOptions) error {
+	if len(animation.Frames) == 0 {
+		return errors.New("The animation has no frames")
+	}
+	switch options.Format {
+	case FORMAT_GIF:
+		return encodeGifAnimation(output, animation, options)
+	case FORMAT_APNG:
+		return encodeApng(output, animation)
+	}
+	return fmt.Errorf("Unsupported animation format: %s", options.Format)
+}
+
+// Writes an animated GIF whose frames share one palette, which is built from
+// the colors of all the frames.
+func encodeGifAnimation(output io.Writer, animation Animation, options OutputOptions) error {
+	palette := MedianCutQuantizer{}.QuantizeImages(make(color.Palette, 0, options.Colors), animation.Frames)
+	frames := make([]*image.Paletted, len(animation.Frames))
+	delays := make([]int, len(animation.Frames))
+	ParallelFor(len(frames), runtime.NumCPU(), func(i int) error {
+		frames[i] = Palettize(animation.Frames[i], palette, options.Dither)
+		delays[i] = int(math.Max(1, math.Round(float64(animation.Delay)/10)))
+		return nil
+	})
+	loopCount := 0
+	if animation.Loops == 1 {
+		loopCount = -1
+	} else if animation.Loops > 1 {
+		loopCount = animation.Loops - 1
+	}
+	return gif.EncodeAll(output, &gif.GIF{Image: frames, Delay: delays, LoopCount: loopCount})
+}
+
+// Maps the colors of an image to the closest colors of a palette, which are
+// dithered with Floyd-Steinberg error diffusion when dither is set.
+func Palettize(img image.Image, palette color.Palette, dither bool) *image.Paletted {
+	bounds := img.Bounds()
+	paletted := image.NewPaletted(bounds, palette)
+	if dither {
+		draw.FloydSteinberg.Draw(paletted, bounds, img, bounds.Min)
+		return paletted
+	}
+	indices := make(map[color.RGBA64]uint8)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := ToRGBA64Color(img.At(x, y))
+			index, ok := indices[c]
+			if !ok {
+				index = uint8(palette.Index(c))
+				indices[c] = index
+			}
+			paletted.SetColorIndex(x, y, index)
+		}
+	}
+	return paletted
+}
+
+// Writes an animated PNG, whose frames are encoded as PNG images and copied
+// into frame control and frame data chunks.
+func encodeApng(output io.Writer, animation Animation) error {
+	frames := make([][]pngChunk, len(animation.Frames))
+	err := ParallelFor(len(frames), runtime.NumCPU(), func(i int) error {
+		var content bytes.Buffer
+		err := png.Encode(&content, animation.Frames[i])
+		if err != nil {
+			return err
+		}
+		frames[i], err = readPngChunks(content.Bytes())
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	header := frames[0][0]
+	for _, chunks := range frames {
+		if chunks[0].Type != "IHDR" || !bytes.Equal(chunks[0].Data, header.Data) {
+			return errors.New("The frames of the animation have different sizes or color types")
+		}
+	}
+	_, err = output.Write(PNG_SIGNATURE)
+	if err != nil {
+		return err
+	}
+	err = writePngChunk(output, header.Type, header.Data)
+	if err != nil {
+		return err
+	}
+	animationControl := make([]byte, 8)
+	binary.BigEndian.PutUint32(animationControl[0:], uint32(len(frames)))
+	binary.BigEndian.PutUint32(animationControl[4:], uint32(animation.Loops))
+	err = writePngChunk(output, "acTL", animationControl)
+	if err != nil {
+		return err
+	}
+	sequence := uint32(0)
+	for i, chunks := range frames {
+		bounds := animation.Frames[i].Bounds()
+		frameControl := make([]byte, 26)
+		binary.BigEndian.PutUint32(frameControl[0:], sequence)
+		binary.BigEndian.PutUint32(frameControl[4:], uint32(bounds.Dx()))
+		binary.BigEndian.PutUint32(frameControl[8:], uint32(bounds.Dy()))
+		binary.BigEndian.PutUint16(frameControl[20:], uint16(animation.Delay))
+		binary.BigEndian.PutUint16(frameControl[22:], APNG_DELAY_DENOMINATOR)
+		err = writePngChunk(output, "fcTL", frameControl)
+		if err != nil {
+			return err
+		}
+		sequence++
+		for _, chunk := range chunks[1:] {
+			if chunk.Type != "IDAT" {
+				if i == 0 && chunk.Type != "IEND" {
+					err = writePngChunk(output, chunk.Type, chunk.Data)
+				}
+			} else if i == 0 {
+				err = writePngChunk(output, chunk.Type, chunk.Data)
+			} else {
+				frameData := make([]byte, 4+len(chunk.Data))
+				binary.BigEndian.PutUint32(frameData, sequence)
+				copy(frameData[4:], chunk.Data)
+				err = writePngChunk(output, "fdAT", frameData)
+				sequence++
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return writePngChunk(output, "IEND", nil)
+}
+
+// Splits the content of a PNG file into its chunks.
+func readPngChunks(content []byte) ([]pngChunk, error) {
+	if !bytes.HasPrefix(content, PNG_SIGNATURE) {
+		return nil, errors.New("Invalid PNG signature")
+	}
+	var chunks []pngChunk
+	content = content[len(PNG_SIGNATURE):]
+	for len(content) > 0 {
+		if len(content) < 12 {
+			return nil, errors.New("Invalid PNG chunk")
+		}
+		length := int(binary.BigEndian.Uint32(content))
+		if len(content) < 12+length {
+			return nil, errors.New("Invalid PNG chunk")
+		}
+		chunks = append(chunks, pngChunk{Type: string(content[4:8]), Data: content[8 : 8+length]})
+		content = content[12+length:]
+	}
+	return chunks, nil
+}
+
+// Writes a chunk of a PNG file with its length and checksum.
+func writePngChunk(output io.Writer, chunkType string, data []byte) error {
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint32(header, uint32(len(data)))
+	copy(header[4:], chunkType)
+	checksum := crc32.NewIEEE()
+	checksum.Write(header[4:])
+	checksum.Write(data)
+	footer := make([]byte, 4)
+	binary.BigEndian.PutUint32(footer, checksum.Sum32())
+	for _, part := range [][]byte{header, data, footer} {
+		_, err := output.Write(part)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
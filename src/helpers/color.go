@@ -34,6 +34,16 @@ func RandomAlphaColor() color.RGBA {
 	return randomColor
 }
 
+// Converts a color to a color with 16 bits per channel.
+func ToRGBA64Color(c color.Color) color.RGBA64 {
+	return color.RGBA64Model.Convert(c).(color.RGBA64)
+}
+
+// Converts a color to a color with 8 bits per channel.
+func ToRGBAColor(c color.Color) color.RGBA {
+	return color.RGBAModel.Convert(c).(color.RGBA)
+}
+
 // Parses a given color value.
 func ParseColor(txt string) (color.RGBA, error) {
 	colorParsers := make(map[string]func(string) (color.RGBA, error), 2)
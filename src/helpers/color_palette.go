@@ -4,19 +4,18 @@ import (
 	"errors"
 	"image"
 	"image/color"
-	"image/png"
-	"io"
+	"image/draw"
 	"math"
 	"os"
 	"strconv"
 	"strings"
 
-	"github.com/llgcode/draw2d/draw2dimg"
 	"gopkg.in/yaml.v3"
 )
 
 var (
-	NIL_COLOR = color.RGBA{0, 0, 0, 0}
+	NIL_COLOR    = color.RGBA{0, 0, 0, 0}
+	NIL_COLOR_64 = color.RGBA64{0, 0, 0, 0}
 )
 
 // Represents a color palette.
@@ -32,10 +31,11 @@ type Transition struct {
 	Position float32 `yaml:"position"`
 }
 
-// Gets the color value of a given position in this ColorPalette.
-func (palette *ColorPalette) GetColor(pos float64) (color.RGBA, error) {
+// Gets the color value of a given position in this ColorPalette. The color
+// is interpolated with 16 bits per channel.
+func (palette *ColorPalette) GetColor(pos float64) (color.RGBA64, error) {
 	if palette.Transitions == nil || len(palette.Transitions) == 0 {
-		return NIL_COLOR, errors.New("ColorPalette has no color transitions")
+		return NIL_COLOR_64, errors.New("ColorPalette has no color transitions")
 	}
 	value := math.Max(0, math.Min(1.0, pos))
 	idx := 0
@@ -46,42 +46,52 @@ func (palette *ColorPalette) GetColor(pos float64) (color.RGBA, error) {
 		idx--
 	}
 	curTransition := palette.Transitions[idx]
+	curColor, err := curTransition.getColor()
+	if err != nil {
+		return NIL_COLOR_64, err
+	}
 	if idx >= len(palette.Transitions)-1 {
-		if palette.Transitions[idx]._Color != nil {
-			return *palette.Transitions[idx]._Color, nil
-		}
-		return ParseColor(palette.Transitions[idx].Color)
+		return ToRGBA64Color(curColor), nil
 	}
 	nextTransition := palette.Transitions[idx+1]
-	var err error
-	var curColor, nextColor color.RGBA
-	if curTransition._Color != nil {
-		curColor = *curTransition._Color
-	} else {
-		curColor, err = ParseColor(curTransition.Color)
-		if err != nil {
-			return NIL_COLOR, err
-		}
-	}
-	if nextTransition._Color != nil {
-		nextColor = *nextTransition._Color
-	} else {
-		nextColor, err = ParseColor(nextTransition.Color)
-		if err != nil {
-			return NIL_COLOR, err
-		}
+	nextColor, err := nextTransition.getColor()
+	if err != nil {
+		return NIL_COLOR_64, err
 	}
 	grad := (value - float64(curTransition.Position))
 	grad /= (float64(nextTransition.Position) - float64(curTransition.Position))
-	posColor := color.RGBA{
-		R: curColor.R + uint8(grad*float64(nextColor.R-curColor.R)),
-		G: curColor.G + uint8(grad*float64(nextColor.G-curColor.G)),
-		B: curColor.B + uint8(grad*float64(nextColor.B-curColor.B)),
-		A: 255,
+	posColor := color.RGBA64{
+		R: interpolateChannel(curColor.R, nextColor.R, grad),
+		G: interpolateChannel(curColor.G, nextColor.G, grad),
+		B: interpolateChannel(curColor.B, nextColor.B, grad),
+		A: 0xffff,
 	}
 	return posColor, nil
 }
 
+// Gets the color of this transition, which is parsed if it hasn't been
+// translated.
+func (transition *Transition) getColor() (color.RGBA, error) {
+	if transition._Color != nil {
+		return *transition._Color, nil
+	}
+	return ParseColor(transition.Color)
+}
+
+// Interpolates between two 8-bit channels into a 16-bit channel.
+func interpolateChannel(a, b uint8, grad float64) uint16 {
+	value := (float64(a) + grad*(float64(b)-float64(a))) * 0x101
+	return uint16(math.Round(math.Max(0, math.Min(0xffff, value))))
+}
+
+// Creates a copy of this palette whose transitions can be translated
+// independently.
+func (palette *ColorPalette) Copy() ColorPalette {
+	transitions := make([]Transition, len(palette.Transitions))
+	copy(transitions, palette.Transitions)
+	return ColorPalette{Name: palette.Name, Transitions: transitions}
+}
+
 // Translate the value of the color transitions for this color palette.
 func (palette *ColorPalette) TranslateColorTransitions() error {
 	for i := 0; i < len(palette.Transitions); i++ {
@@ -94,31 +104,28 @@ func (palette *ColorPalette) TranslateColorTransitions() error {
 	return nil
 }
 
-// Displays an image of this palette to the given output.
-func (palette *ColorPalette) Render(output io.Writer, width, height int, step float64) error {
+// Creates an image of this palette with the given number of bits per
+// channel.
+func (palette *ColorPalette) CreateImage(width, height int, step float64, bitDepth int) (image.Image, error) {
 	viewport := image.Rect(0, 0, width, height)
-	img := image.NewRGBA(viewport)
-	gc := draw2dimg.NewGraphicContext(img)
+	img := NewImage(viewport, bitDepth)
 	err := palette.TranslateColorTransitions()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if step <= 0 {
-		return errors.New("steps between transitions must be greater than 0")
+		return nil, errors.New("steps between transitions must be greater than 0")
 	}
 	for x := 0.0; x <= float64(width); x += step {
 		pos := x / float64(width)
 		curColor, err := palette.GetColor(pos)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		FillRectangle(gc, x, 0.0, step, float64(height), curColor)
+		stripe := image.Rect(int(math.Round(x)), 0, int(math.Round(x+step)), height)
+		draw.Draw(img, stripe, image.NewUniform(curColor), image.Point{}, draw.Src)
 	}
-	err = png.Encode(output, img)
-	if err != nil {
-		return err
-	}
-	return nil
+	return img, nil
 }
 
 // Parses a string into a color palette.
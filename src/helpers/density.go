@@ -0,0 +1,85 @@
+package helpers
+
+import (
+	"image/draw"
+	"math"
+)
+
+const (
+	TONE_MAPPING_LOG   = "log"
+	TONE_MAPPING_GAMMA = "gamma"
+)
+
+// Represents the number of times each pixel of an image has been hit.
+type DensityBuffer struct {
+	Width  int
+	Height int
+	Counts []uint32
+	Max    uint32
+}
+
+// Creates a DensityBuffer for an image of the given size.
+func NewDensityBuffer(width, height int) *DensityBuffer {
+	return &DensityBuffer{
+		Width:  width,
+		Height: height,
+		Counts: make([]uint32, width*height),
+	}
+}
+
+// Increments the number of hits of a pixel. Pixels outside the buffer are
+// ignored.
+func (buffer *DensityBuffer) Hit(x, y int) {
+	if x < 0 || y < 0 || x >= buffer.Width || y >= buffer.Height {
+		return
+	}
+	i := y*buffer.Width + x
+	buffer.Counts[i]++
+	if buffer.Counts[i] > buffer.Max {
+		buffer.Max = buffer.Counts[i]
+	}
+}
+
+// Computes the tone-mapped value of a pixel in the range 0 to 1.
+//  *toneMapping*: The curve used for mapping hits to values (log or gamma).
+//  *gamma*: The gamma of the gamma curve.
+func (buffer *DensityBuffer) Value(x, y int, toneMapping string, gamma float64) float64 {
+	count := buffer.Counts[y*buffer.Width+x]
+	if count == 0 || buffer.Max == 0 {
+		return 0
+	}
+	if toneMapping == TONE_MAPPING_GAMMA {
+		return math.Pow(float64(count)/float64(buffer.Max), 1/gamma)
+	}
+	return math.Log1p(float64(count)) / math.Log1p(float64(buffer.Max))
+}
+
+// Colors the pixels of an image that have been hit using a color palette.
+//  *img*: The image to color.
+//  *palette*: The color palette for coloring the tone-mapped values.
+//  *toneMapping*: The curve used for mapping hits to values (log or gamma).
+//  *gamma*: The gamma of the gamma curve.
+func (buffer *DensityBuffer) ToneMap(img draw.RGBA64Image, palette ColorPalette, toneMapping string, gamma float64) error {
+	err := palette.TranslateColorTransitions()
+	if err != nil {
+		return err
+	}
+	for y := 0; y < buffer.Height; y++ {
+		for x := 0; x < buffer.Width; x++ {
+			if buffer.Counts[y*buffer.Width+x] == 0 {
+				continue
+			}
+			pixelColor, err := palette.GetColor(buffer.Value(x, y, toneMapping, gamma))
+			if err != nil {
+				return err
+			}
+			img.SetRGBA64(x, y, pixelColor)
+		}
+	}
+	return nil
+}
+
+// Checks if a tone mapping curve is supported.
+func IsValidToneMapping(txt string) bool {
+	return txt == TONE_MAPPING_LOG || txt == TONE_MAPPING_GAMMA
+}
@@ -2,6 +2,7 @@ package helpers
 
 import (
 	"errors"
+	"math"
 	"strconv"
 )
 
@@ -55,3 +56,46 @@ func ParseRect(txt string) (Rect, error) {
 	}
 	return EMPTY_REGION, errors.New("Invalid rect")
 }
+
+// Converts a CSV of two floats to a Point type.
+func ParsePoint(txt string) (Point, error) {
+	values, err := GetCSV(txt)
+	if err != nil {
+		return Point{}, err
+	}
+	if len(values) != 2 {
+		return Point{}, errors.New("Invalid point")
+	}
+	x, err := strconv.ParseFloat(values[0], 64)
+	if err != nil {
+		return Point{}, err
+	}
+	y, err := strconv.ParseFloat(values[1], 64)
+	if err != nil {
+		return Point{}, err
+	}
+	return Point{X: x, Y: y}, nil
+}
+
+// Computes the scale and offsets that fit a region into an image of the
+// given size, keeping its aspect ratio and centering it.
+//  *bounds*: The region to fit.
+//  *margin*: The space to leave around the region, in pixels.
+func FitRect(bounds Rect, width, height, margin float64) (scale, xOffset, yOffset float64) {
+	availableWidth := math.Max(width-2*margin, 1)
+	availableHeight := math.Max(height-2*margin, 1)
+	boundsWidth, boundsHeight := bounds.Width, bounds.Height
+	if boundsWidth <= 0 && boundsHeight <= 0 {
+		return 1, width/2 - bounds.X, height/2 - bounds.Y
+	}
+	if boundsWidth <= 0 {
+		scale = availableHeight / boundsHeight
+	} else if boundsHeight <= 0 {
+		scale = availableWidth / boundsWidth
+	} else {
+		scale = math.Min(availableWidth/boundsWidth, availableHeight/boundsHeight)
+	}
+	xOffset = -bounds.X*scale + (width-boundsWidth*scale)/2
+	yOffset = -bounds.Y*scale + (height-boundsHeight*scale)/2
+	return scale, xOffset, yOffset
+}
@@ -3,13 +3,17 @@ package helpers
 import (
 	"image"
 	"image/color"
+	"image/draw"
 
 	"github.com/llgcode/draw2d"
 	"github.com/llgcode/draw2d/draw2dimg"
+	"github.com/llgcode/draw2d/draw2dkit"
 )
 
 const (
-	LINE_WIDTH = 0.2
+	LINE_WIDTH   = 0.2
+	BIT_DEPTH_8  = 8
+	BIT_DEPTH_16 = 16
 )
 
 // Represents a rectangular region.
@@ -25,17 +29,34 @@ type Point struct {
 	Y float64 `json:"y"`
 }
 
+// Creates an image with the given number of bits per channel, which is 8
+// unless it's 16.
+func NewImage(viewport image.Rectangle, bitDepth int) draw.RGBA64Image {
+	if bitDepth == BIT_DEPTH_16 {
+		return image.NewRGBA64(viewport)
+	}
+	return image.NewRGBA(viewport)
+}
+
 // Fills an image with color.
-//  *image*: The image to fill.
+//  *img*: The image to fill.
 //  *color*: The color to fill the image with.
-func FillImage(image *image.RGBA, color color.RGBA) {
-	width := image.Bounds().Dx()
-	height := image.Bounds().Dy()
-	for i := 0; i <= width; i++ {
-		for j := 0; j <= height; j++ {
-			image.SetRGBA(i, j, color)
-		}
+func FillImage(img draw.Image, color color.Color) {
+	draw.Draw(img, img.Bounds(), image.NewUniform(color), image.Point{}, draw.Src)
+}
+
+// Draws over an image with a graphic context. Images that don't have 8 bits
+// per channel are drawn over with a transparent layer.
+//  *img*: The image to draw over.
+//  *drawLayer*: The function that draws with the graphic context.
+func DrawOver(img draw.Image, drawLayer func(gc draw2d.GraphicContext)) {
+	if rgba, ok := img.(*image.RGBA); ok {
+		drawLayer(draw2dimg.NewGraphicContext(rgba))
+		return
 	}
+	layer := image.NewRGBA(img.Bounds())
+	drawLayer(draw2dimg.NewGraphicContext(layer))
+	draw.Draw(img, img.Bounds(), layer, img.Bounds().Min, draw.Over)
 }
 
 // Draws a rectangle in an image.
@@ -45,7 +66,7 @@ func FillImage(image *image.RGBA, color color.RGBA) {
 //  *width*: The width of the rectangle.
 //  *height*: The height of the rectangle.
 //  *color*: The color to stroke the rectangle with.
-func DrawRectangle(gc *draw2dimg.GraphicContext, x, y, width, height float64, color color.RGBA) {
+func DrawRectangle(gc draw2d.GraphicContext, x, y, width, height float64, color color.RGBA) {
 	gc.SetStrokeColor(color)
 	gc.SetLineWidth(LINE_WIDTH)
 	gc.BeginPath()
@@ -65,7 +86,7 @@ func DrawRectangle(gc *draw2dimg.GraphicContext, x, y, width, height float64, co
 //  *width*: The width of the rectangle.
 //  *height*: The height of the rectangle.
 //  *color*: The color to fill the rectangle with.
-func FillRectangle(gc *draw2dimg.GraphicContext, x, y, width, height float64, color color.RGBA) {
+func FillRectangle(gc draw2d.GraphicContext, x, y, width, height float64, color color.RGBA) {
 	gc.SetFillColor(color)
 	gc.SetStrokeColor(color)
 	gc.SetLineWidth(LINE_WIDTH)
@@ -83,7 +104,7 @@ func FillRectangle(gc *draw2dimg.GraphicContext, x, y, width, height float64, co
 //  *pt1*: The starting point of the line.
 //  *pt2*: The ending point of the line.
 //  *color*: The color to stroke the line with.
-func DrawLine(gc *draw2dimg.GraphicContext, pt1, pt2 Point, color color.RGBA) {
+func DrawLine(gc draw2d.GraphicContext, pt1, pt2 Point, color color.RGBA) {
 	gc.SetStrokeColor(color)
 	gc.SetLineWidth(LINE_WIDTH)
 	gc.BeginPath()
@@ -98,7 +119,7 @@ func DrawLine(gc *draw2dimg.GraphicContext, pt1, pt2 Point, color color.RGBA) {
 //  *pt2*: The second point of the triangle.
 //  *pt3*: The third point of the triangle.
 //  *strokeColor*: The color to stroke the triangle with.
-func DrawTriangle(gc *draw2dimg.GraphicContext, pt1, pt2, pt3 Point, strokeColor color.RGBA) {
+func DrawTriangle(gc draw2d.GraphicContext, pt1, pt2, pt3 Point, strokeColor color.RGBA) {
 	gc.SetStrokeColor(strokeColor)
 	gc.SetLineWidth(LINE_WIDTH)
 	gc.BeginPath()
@@ -116,7 +137,7 @@ func DrawTriangle(gc *draw2dimg.GraphicContext, pt1, pt2, pt3 Point, strokeColor
 //  *pt3*: The third point of the triangle.
 //  *strokeColor*: The color to stroke the triangle with.
 //  *fillColor*: The color to fill the triangle with.
-func DrawFilledTriangle(gc *draw2dimg.GraphicContext, pt1, pt2, pt3 Point, strokeColor, fillColor color.RGBA) {
+func DrawFilledTriangle(gc draw2d.GraphicContext, pt1, pt2, pt3 Point, strokeColor, fillColor color.RGBA) {
 	gc.SetStrokeColor(strokeColor)
 	gc.SetFillColor(fillColor)
 	gc.SetLineWidth(LINE_WIDTH)
@@ -132,7 +153,7 @@ func DrawFilledTriangle(gc *draw2dimg.GraphicContext, pt1, pt2, pt3 Point, strok
 // Adds a pixel to an image.
 //  *x*: The horizontal position of the pixel.
 //  *y*: The vertical position of the pixel.
-func PutPixel(gc *draw2dimg.GraphicContext, x, y float64, color color.RGBA) {
+func PutPixel(gc draw2d.GraphicContext, x, y float64, color color.RGBA) {
 	gc.SetFillColor(color)
 	gc.SetStrokeColor(color)
 	gc.SetLineWidth(LINE_WIDTH)
@@ -146,3 +167,19 @@ func PutPixel(gc *draw2dimg.GraphicContext, x, y float64, color color.RGBA) {
 	gc.Close()
 	gc.FillStroke()
 }
+
+// Draws a filled circle in an image.
+//  *x*: The horizontal position of the center of the circle.
+//  *y*: The vertical position of the center of the circle.
+//  *radius*: The radius of the circle.
+//  *strokeColor*: The color to stroke the circle with.
+//  *fillColor*: The color to fill the circle with.
+func DrawFilledCircle(gc draw2d.GraphicContext, x, y, radius float64, strokeColor, fillColor color.RGBA) {
+	gc.SetStrokeColor(strokeColor)
+	gc.SetFillColor(fillColor)
+	gc.SetLineWidth(LINE_WIDTH)
+	gc.BeginPath()
+	draw2dkit.Circle(gc, x, y, radius)
+	gc.Close()
+	gc.FillStroke()
+}
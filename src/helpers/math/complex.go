@@ -0,0 +1,36 @@
+package math
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Parses a complex number, allowing the imaginary unit to be written
+// without a coefficient (i.e. i, -i, or 1-i).
+func ParseComplex(txt string) (complex128, error) {
+	text := strings.ReplaceAll(strings.TrimSpace(txt), " ", "")
+	if len(text) == 0 {
+		return 0, errors.New("Invalid complex number")
+	}
+	if strings.HasSuffix(text, "i") {
+		prefix := text[:len(text)-1]
+		if len(prefix) == 0 || strings.HasSuffix(prefix, "+") || strings.HasSuffix(prefix, "-") {
+			text = prefix + "1i"
+		}
+	}
+	return strconv.ParseComplex(text, 128)
+}
+
+// Converts a slice of strings to a slice of complex numbers.
+func ParseComplexList(values []string) ([]complex128, error) {
+	numbers := make([]complex128, len(values))
+	for i, value := range values {
+		number, err := ParseComplex(value)
+		if err != nil {
+			return nil, err
+		}
+		numbers[i] = number
+	}
+	return numbers, nil
+}
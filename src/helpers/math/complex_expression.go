@@ -0,0 +1,528 @@
+package math
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/cmplx"
+	"strconv"
+	"unicode"
+)
+
+var (
+	NIL_CMPLX_EXPRESSION = CmplxExpression{}
+
+	// The step scale used for numeric derivatives, which is the cube root
+	// of the machine epsilon.
+	NUMERIC_DERIVATIVE_STEP = math.Cbrt(2.220446049250313e-16)
+
+	EXPRESSION_CONSTANTS = map[string]complex128{
+		"i":  1i,
+		"e":  complex(math.E, 0),
+		"pi": complex(math.Pi, 0),
+	}
+
+	// The functions that can be used in an expression.
+	EXPRESSION_FUNCTIONS = map[string]func(complex128) complex128{
+		"sin":   cmplx.Sin,
+		"cos":   cmplx.Cos,
+		"tan":   cmplx.Tan,
+		"cot":   cmplx.Cot,
+		"sinh":  cmplx.Sinh,
+		"cosh":  cmplx.Cosh,
+		"tanh":  cmplx.Tanh,
+		"asin":  cmplx.Asin,
+		"acos":  cmplx.Acos,
+		"atan":  cmplx.Atan,
+		"asinh": cmplx.Asinh,
+		"acosh": cmplx.Acosh,
+		"atanh": cmplx.Atanh,
+		"exp":   cmplx.Exp,
+		"log":   cmplx.Log,
+		"ln":    cmplx.Log,
+		"sqrt":  cmplx.Sqrt,
+	}
+
+	// The derivatives of the functions that can be used in an expression
+	// with respect to their argument. Functions without a symbolic
+	// derivative are missing from this map.
+	EXPRESSION_FUNCTION_DERIVATIVES = map[string]func(expressionNode) expressionNode{
+		"sin": func(u expressionNode) expressionNode { return newFunction("cos", u) },
+		"cos": func(u expressionNode) expressionNode { return newNegation(newFunction("sin", u)) },
+		"tan": func(u expressionNode) expressionNode {
+			return newQuotient(newConstant(1), newPower(newFunction("cos", u), newConstant(2)))
+		},
+		"cot": func(u expressionNode) expressionNode {
+			return newNegation(newQuotient(newConstant(1), newPower(newFunction("sin", u), newConstant(2))))
+		},
+		"sinh": func(u expressionNode) expressionNode { return newFunction("cosh", u) },
+		"cosh": func(u expressionNode) expressionNode { return newFunction("sinh", u) },
+		"tanh": func(u expressionNode) expressionNode {
+			return newQuotient(newConstant(1), newPower(newFunction("cosh", u), newConstant(2)))
+		},
+		"asin": func(u expressionNode) expressionNode {
+			return newQuotient(newConstant(1), newFunction("sqrt", newDifference(newConstant(1), newPower(u, newConstant(2)))))
+		},
+		"acos": func(u expressionNode) expressionNode {
+			return newNegation(newQuotient(newConstant(1), newFunction("sqrt", newDifference(newConstant(1), newPower(u, newConstant(2))))))
+		},
+		"atan": func(u expressionNode) expressionNode {
+			return newQuotient(newConstant(1), newSum(newConstant(1), newPower(u, newConstant(2))))
+		},
+		"asinh": func(u expressionNode) expressionNode {
+			return newQuotient(newConstant(1), newFunction("sqrt", newSum(newPower(u, newConstant(2)), newConstant(1))))
+		},
+		"acosh": func(u expressionNode) expressionNode {
+			return newQuotient(newConstant(1), newProduct(
+				newFunction("sqrt", newDifference(u, newConstant(1))),
+				newFunction("sqrt", newSum(u, newConstant(1))),
+			))
+		},
+		"atanh": func(u expressionNode) expressionNode {
+			return newQuotient(newConstant(1), newDifference(newConstant(1), newPower(u, newConstant(2))))
+		},
+		"exp": func(u expressionNode) expressionNode { return newFunction("exp", u) },
+		"log": func(u expressionNode) expressionNode { return newQuotient(newConstant(1), u) },
+		"ln":  func(u expressionNode) expressionNode { return newQuotient(newConstant(1), u) },
+		"sqrt": func(u expressionNode) expressionNode {
+			return newQuotient(newConstant(1), newProduct(newConstant(2), newFunction("sqrt", u)))
+		},
+	}
+)
+
+// Represents a node of the syntax tree of an expression.
+type expressionNode interface {
+	evaluate(z complex128) complex128
+	derivative() (expressionNode, error)
+	isConstant() bool
+	toString(variable rune) string
+}
+
+// Represents a complex expression of a single variable.
+type CmplxExpression struct {
+	root     expressionNode
+	Variable rune
+}
+
+// Evaluates the value of a complex expression for a given z.
+func (expression *CmplxExpression) Evaluate(z complex128) complex128 {
+	return expression.root.evaluate(z)
+}
+
+// Computes the symbolic first derivative of a complex expression.
+func (expression *CmplxExpression) FirstDerivative() (CmplxExpression, error) {
+	deriv, err := expression.root.derivative()
+	if err != nil {
+		return NIL_CMPLX_EXPRESSION, err
+	}
+	return CmplxExpression{root: deriv, Variable: expression.Variable}, nil
+}
+
+// Converts a CmplxExpression type to its string representation.
+func (expression *CmplxExpression) ToString() string {
+	return expression.root.toString(expression.Variable)
+}
+
+// Creates a function that computes the numeric first derivative of a
+// given function using a central difference.
+func NumericDerivative(fxn func(complex128) complex128) func(complex128) complex128 {
+	return func(z complex128) complex128 {
+		h := complex(NUMERIC_DERIVATIVE_STEP*math.Max(1, cmplx.Abs(z)), 0)
+		return (fxn(z+h) - fxn(z-h)) / (2 * h)
+	}
+}
+
+type constantNode struct {
+	value complex128
+}
+
+type variableNode struct{}
+
+type negationNode struct {
+	operand expressionNode
+}
+
+type binaryNode struct {
+	operator rune
+	left     expressionNode
+	right    expressionNode
+}
+
+type functionNode struct {
+	name     string
+	argument expressionNode
+}
+
+func (node *constantNode) evaluate(z complex128) complex128 { return node.value }
+
+func (node *constantNode) derivative() (expressionNode, error) { return newConstant(0), nil }
+
+func (node *constantNode) isConstant() bool { return true }
+
+func (node *constantNode) toString(variable rune) string {
+	if imag(node.value) == 0 {
+		return strconv.FormatFloat(real(node.value), byte('g'), -1, 64)
+	}
+	return strconv.FormatComplex(node.value, byte('g'), -1, 128)
+}
+
+func (node *variableNode) evaluate(z complex128) complex128 { return z }
+
+func (node *variableNode) derivative() (expressionNode, error) { return newConstant(1), nil }
+
+func (node *variableNode) isConstant() bool { return false }
+
+func (node *variableNode) toString(variable rune) string { return string(variable) }
+
+func (node *negationNode) evaluate(z complex128) complex128 { return -node.operand.evaluate(z) }
+
+func (node *negationNode) derivative() (expressionNode, error) {
+	deriv, err := node.operand.derivative()
+	if err != nil {
+		return nil, err
+	}
+	return newNegation(deriv), nil
+}
+
+func (node *negationNode) isConstant() bool { return node.operand.isConstant() }
+
+func (node *negationNode) toString(variable rune) string {
+	return "-(" + node.operand.toString(variable) + ")"
+}
+
+func (node *binaryNode) evaluate(z complex128) complex128 {
+	left, right := node.left.evaluate(z), node.right.evaluate(z)
+	switch node.operator {
+	case '+':
+		return left + right
+	case '-':
+		return left - right
+	case '*':
+		return left * right
+	case '/':
+		return left / right
+	}
+	return cmplx.Pow(left, right)
+}
+
+func (node *binaryNode) derivative() (expressionNode, error) {
+	leftDeriv, err := node.left.derivative()
+	if err != nil {
+		return nil, err
+	}
+	rightDeriv, err := node.right.derivative()
+	if err != nil {
+		return nil, err
+	}
+	u, v := node.left, node.right
+	switch node.operator {
+	case '+':
+		return newSum(leftDeriv, rightDeriv), nil
+	case '-':
+		return newDifference(leftDeriv, rightDeriv), nil
+	case '*':
+		return newSum(newProduct(leftDeriv, v), newProduct(u, rightDeriv)), nil
+	case '/':
+		return newQuotient(
+			newDifference(newProduct(leftDeriv, v), newProduct(u, rightDeriv)),
+			newPower(v, newConstant(2)),
+		), nil
+	}
+	if v.isConstant() {
+		// d(u^n) = n * u^(n - 1) * du
+		return newProduct(newProduct(v, newPower(u, newDifference(v, newConstant(1)))), leftDeriv), nil
+	}
+	// d(u^v) = u^v * (dv * ln(u) + v * du / u)
+	return newProduct(
+		newPower(u, v),
+		newSum(newProduct(rightDeriv, newFunction("log", u)), newQuotient(newProduct(v, leftDeriv), u)),
+	), nil
+}
+
+func (node *binaryNode) isConstant() bool { return node.left.isConstant() && node.right.isConstant() }
+
+func (node *binaryNode) toString(variable rune) string {
+	return "(" + node.left.toString(variable) + string(node.operator) + node.right.toString(variable) + ")"
+}
+
+func (node *functionNode) evaluate(z complex128) complex128 {
+	return EXPRESSION_FUNCTIONS[node.name](node.argument.evaluate(z))
+}
+
+func (node *functionNode) derivative() (expressionNode, error) {
+	fxnDeriv, ok := EXPRESSION_FUNCTION_DERIVATIVES[node.name]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("%s has no symbolic derivative", node.name))
+	}
+	argumentDeriv, err := node.argument.derivative()
+	if err != nil {
+		return nil, err
+	}
+	return newProduct(fxnDeriv(node.argument), argumentDeriv), nil
+}
+
+func (node *functionNode) isConstant() bool { return node.argument.isConstant() }
+
+func (node *functionNode) toString(variable rune) string {
+	return node.name + "(" + node.argument.toString(variable) + ")"
+}
+
+// The constructors below fold constants and drop identity operations so
+// that repeated derivatives stay small.
+
+func newConstant(value complex128) expressionNode {
+	return &constantNode{value: value}
+}
+
+func newFunction(name string, argument expressionNode) expressionNode {
+	node := &functionNode{name: name, argument: argument}
+	if argument.isConstant() {
+		return newConstant(node.evaluate(0))
+	}
+	return node
+}
+
+func newNegation(operand expressionNode) expressionNode {
+	if operand.isConstant() {
+		return newConstant(-operand.evaluate(0))
+	}
+	if negation, ok := operand.(*negationNode); ok {
+		return negation.operand
+	}
+	return &negationNode{operand: operand}
+}
+
+func newBinary(operator rune, left, right expressionNode) expressionNode {
+	node := &binaryNode{operator: operator, left: left, right: right}
+	if node.isConstant() {
+		return newConstant(node.evaluate(0))
+	}
+	return node
+}
+
+func isConstantValue(node expressionNode, value complex128) bool {
+	return node.isConstant() && node.evaluate(0) == value
+}
+
+func newSum(left, right expressionNode) expressionNode {
+	if isConstantValue(left, 0) {
+		return right
+	}
+	if isConstantValue(right, 0) {
+		return left
+	}
+	return newBinary('+', left, right)
+}
+
+func newDifference(left, right expressionNode) expressionNode {
+	if isConstantValue(right, 0) {
+		return left
+	}
+	if isConstantValue(left, 0) {
+		return newNegation(right)
+	}
+	return newBinary('-', left, right)
+}
+
+func newProduct(left, right expressionNode) expressionNode {
+	if isConstantValue(left, 0) || isConstantValue(right, 0) {
+		return newConstant(0)
+	}
+	if isConstantValue(left, 1) {
+		return right
+	}
+	if isConstantValue(right, 1) {
+		return left
+	}
+	return newBinary('*', left, right)
+}
+
+func newQuotient(left, right expressionNode) expressionNode {
+	if isConstantValue(left, 0) {
+		return newConstant(0)
+	}
+	if isConstantValue(right, 1) {
+		return left
+	}
+	return newBinary('/', left, right)
+}
+
+func newPower(left, right expressionNode) expressionNode {
+	if isConstantValue(right, 0) {
+		return newConstant(1)
+	}
+	if isConstantValue(right, 1) {
+		return left
+	}
+	return newBinary('^', left, right)
+}
+
+// Represents the state of an expression parser.
+type expressionParser struct {
+	chars    []rune
+	pos      int
+	variable rune
+}
+
+// Constructs a CmplxExpression type from a mathematical expression.
+//
+// Expressions follow the conventions of polynomials, where the variable is
+// a single letter and a number can be multiplied by a term by writing it
+// before the term (e.g. 2.3x). The operators +, -, *, /, and ^ and
+// parentheses are supported, along with the constants i, e, and pi and the
+// functions in EXPRESSION_FUNCTIONS.
+func ParseCmplxExpression(txt string) (CmplxExpression, error) {
+	parser := expressionParser{chars: []rune(txt), variable: ' '}
+	root, err := parser.parseSum()
+	if err != nil {
+		return NIL_CMPLX_EXPRESSION, err
+	}
+	parser.skipSpaces()
+	if parser.pos < len(parser.chars) {
+		return NIL_CMPLX_EXPRESSION, errors.New(fmt.Sprintf("Invalid expression at position %d", parser.pos+1))
+	}
+	return CmplxExpression{root: root, Variable: parser.variable}, nil
+}
+
+func (parser *expressionParser) skipSpaces() {
+	for parser.pos < len(parser.chars) && unicode.IsSpace(parser.chars[parser.pos]) {
+		parser.pos++
+	}
+}
+
+func (parser *expressionParser) peek() rune {
+	parser.skipSpaces()
+	if parser.pos < len(parser.chars) {
+		return parser.chars[parser.pos]
+	}
+	return 0
+}
+
+func (parser *expressionParser) parseSum() (expressionNode, error) {
+	node, err := parser.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		c := parser.peek()
+		if c != '+' && c != '-' {
+			return node, nil
+		}
+		parser.pos++
+		right, err := parser.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		node = newBinary(c, node, right)
+	}
+}
+
+func (parser *expressionParser) parseProduct() (expressionNode, error) {
+	node, err := parser.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		c := parser.peek()
+		operator := '*'
+		if c == '*' || c == '/' {
+			operator = c
+			parser.pos++
+		} else if !(c == '(' || c == '.' || unicode.IsLetter(c) || unicode.IsDigit(c)) {
+			return node, nil
+		}
+		right, err := parser.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		node = newBinary(operator, node, right)
+	}
+}
+
+func (parser *expressionParser) parseUnary() (expressionNode, error) {
+	c := parser.peek()
+	if c == '+' || c == '-' {
+		parser.pos++
+		operand, err := parser.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if c == '-' {
+			return newNegation(operand), nil
+		}
+		return operand, nil
+	}
+	return parser.parsePower()
+}
+
+func (parser *expressionParser) parsePower() (expressionNode, error) {
+	base, err := parser.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if parser.peek() == '^' {
+		parser.pos++
+		exponent, err := parser.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return newBinary('^', base, exponent), nil
+	}
+	return base, nil
+}
+
+func (parser *expressionParser) parsePrimary() (expressionNode, error) {
+	c := parser.peek()
+	start := parser.pos
+	if c == '(' {
+		parser.pos++
+		node, err := parser.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		if parser.peek() != ')' {
+			return nil, errors.New(fmt.Sprintf("Missing closing parenthesis for position %d", start+1))
+		}
+		parser.pos++
+		return node, nil
+	}
+	if unicode.IsDigit(c) || c == '.' {
+		for parser.pos < len(parser.chars) && (unicode.IsDigit(parser.chars[parser.pos]) || parser.chars[parser.pos] == '.') {
+			parser.pos++
+		}
+		value, err := strconv.ParseFloat(string(parser.chars[start:parser.pos]), 64)
+		if err != nil {
+			return nil, err
+		}
+		return newConstant(complex(value, 0)), nil
+	}
+	if unicode.IsLetter(c) {
+		for parser.pos < len(parser.chars) && unicode.IsLetter(parser.chars[parser.pos]) {
+			parser.pos++
+		}
+		name := string(parser.chars[start:parser.pos])
+		if _, ok := EXPRESSION_FUNCTIONS[name]; ok && parser.peek() == '(' {
+			argument, err := parser.parsePrimary()
+			if err != nil {
+				return nil, err
+			}
+			return newFunction(name, argument), nil
+		}
+		if value, ok := EXPRESSION_CONSTANTS[name]; ok {
+			return newConstant(value), nil
+		}
+		if len(name) == 1 {
+			variable := parser.chars[start]
+			if parser.variable != ' ' && variable != parser.variable {
+				return nil, errors.New("Multiple variables in expression")
+			}
+			parser.variable = variable
+			return &variableNode{}, nil
+		}
+		return nil, errors.New(fmt.Sprintf("Unknown function or variable: %s", name))
+	}
+	if c == 0 {
+		return nil, errors.New("Unexpected end of expression")
+	}
+	return nil, errors.New(fmt.Sprintf("Invalid expression at position %d", start+1))
+}
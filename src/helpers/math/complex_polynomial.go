@@ -2,6 +2,7 @@ package math
 
 import (
 	"errors"
+	"math"
 	"math/cmplx"
 	"strconv"
 	"strings"
@@ -22,6 +23,16 @@ const (
 	term_pow_sign      = 4
 	term_pos_pow_start = 5
 	term_pos_pow_end   = 6
+
+	// The default maximum number of iterations used when finding roots.
+	ROOTS_DEFAULT_MAX_ITERATIONS = 500
+	// The default tolerance used when finding roots.
+	ROOTS_DEFAULT_TOLERANCE = 1e-12
+	// The machine epsilon of float64 values.
+	ROOTS_EPSILON = 0x1p-52
+	// The largest value of a polynomial at a root that has converged, as a
+	// multiple of the rounding error of evaluating the polynomial.
+	ROOTS_RESIDUAL_FACTOR = 4
 )
 
 var (
@@ -30,7 +41,7 @@ var (
 
 // Represents a term of a complex polynomial.
 type PolynomialTerm struct {
-	Coefficient float64
+	Coefficient complex128
 	Power       int
 }
 
@@ -53,7 +64,7 @@ func (polynomial *CmplxPolynomial) FirstDerivative() CmplxPolynomial {
 	for _, term := range polynomial.Terms {
 		if term.Power != 0 {
 			derivTerms[i] = PolynomialTerm{
-				Coefficient: term.Coefficient * float64(term.Power),
+				Coefficient: term.Coefficient * complex(float64(term.Power), 0),
 				Power:       term.Power - 1,
 			}
 			i++
@@ -66,7 +77,7 @@ func (polynomial *CmplxPolynomial) FirstDerivative() CmplxPolynomial {
 func (polynomial *CmplxPolynomial) Evaluate(z complex128) complex128 {
 	value := 0.0 + 0i
 	for i := 0; i < len(polynomial.Terms); i++ {
-		a := complex(polynomial.Terms[i].Coefficient, 0)
+		a := polynomial.Terms[i].Coefficient
 		if polynomial.Terms[i].Power == 0 {
 			value += a
 		} else {
@@ -81,10 +92,17 @@ func (polynomial *CmplxPolynomial) Evaluate(z complex128) complex128 {
 func (polynomial *CmplxPolynomial) ToString() string {
 	var sb strings.Builder
 	for i, term := range polynomial.Terms {
-		if i > 0 && term.Coefficient > 0 {
-			sb.WriteRune('+')
+		if imag(term.Coefficient) != 0 {
+			if i > 0 {
+				sb.WriteRune('+')
+			}
+			sb.WriteString(strconv.FormatComplex(term.Coefficient, byte('f'), 4, 128))
+		} else {
+			if i > 0 && real(term.Coefficient) > 0 {
+				sb.WriteRune('+')
+			}
+			sb.WriteString(strconv.FormatFloat(real(term.Coefficient), byte('f'), 4, 64))
 		}
-		sb.WriteString(strconv.FormatFloat(term.Coefficient, byte('f'), 4, 64))
 		if term.Power != 0 {
 			sb.WriteRune(polynomial.Variable)
 			if term.Power != 1 {
@@ -96,6 +114,163 @@ func (polynomial *CmplxPolynomial) ToString() string {
 	return sb.String()
 }
 
+// Retrieves the degree of a complex polynomial.
+func (polynomial *CmplxPolynomial) Degree() int {
+	degree := 0
+	coefficients := polynomial.Coefficients()
+	for i := len(coefficients) - 1; i >= 0; i-- {
+		if coefficients[i] != 0 {
+			degree = i
+			break
+		}
+	}
+	return degree
+}
+
+// Retrieves the coefficients of a complex polynomial, where the index of
+// each coefficient is the power of its term. Terms with negative powers
+// are ignored.
+func (polynomial *CmplxPolynomial) Coefficients() []complex128 {
+	maxPower := 0
+	for _, term := range polynomial.Terms {
+		if term.Power > maxPower {
+			maxPower = term.Power
+		}
+	}
+	coefficients := make([]complex128, maxPower+1)
+	for _, term := range polynomial.Terms {
+		if term.Power >= 0 {
+			coefficients[term.Power] += term.Coefficient
+		}
+	}
+	return coefficients
+}
+
+// Finds the roots of a complex polynomial using the Durand-Kerner method.
+// Roots at 0 are found exactly from the lowest power of the polynomial.
+func (polynomial *CmplxPolynomial) Roots(maxIterations int, tolerance float64) ([]complex128, error) {
+	for _, term := range polynomial.Terms {
+		if term.Power < 0 {
+			return nil, errors.New("Cannot find the roots of a polynomial with negative powers")
+		}
+	}
+	coefficients := polynomial.Coefficients()
+	degree := polynomial.Degree()
+	if degree < 1 {
+		return nil, errors.New("Cannot find the roots of a constant polynomial")
+	}
+	// the roots at 0 are exact, so the polynomial is divided by the power of
+	// its lowest term and only the roots of the quotient are searched for
+	zeros := 0
+	for coefficients[zeros] == 0 {
+		zeros++
+	}
+	coefficients = coefficients[zeros:]
+	degree -= zeros
+	if degree == 0 {
+		return make([]complex128, zeros), nil
+	}
+	leading := coefficients[degree]
+	monic := make([]complex128, degree+1)
+	for i := 0; i <= degree; i++ {
+		monic[i] = coefficients[i] / leading
+	}
+	evaluate := func(z complex128) complex128 {
+		value := 0.0 + 0i
+		for i := degree; i >= 0; i-- {
+			value = value*z + monic[i]
+		}
+		return value
+	}
+	roots := make([]complex128, degree)
+	seed := complex(0.4, 0.9)
+	roots[0] = 1
+	for i := 1; i < degree; i++ {
+		roots[i] = roots[i-1] * seed
+	}
+	for n := 0; n < maxIterations; n++ {
+		maxDelta := 0.0
+		for i := 0; i < degree; i++ {
+			denominator := 1.0 + 0i
+			for j := 0; j < degree; j++ {
+				if i != j {
+					denominator *= roots[i] - roots[j]
+				}
+			}
+			if denominator == 0 {
+				denominator = complex(tolerance, tolerance)
+			}
+			delta := evaluate(roots[i]) / denominator
+			roots[i] -= delta
+			maxDelta = math.Max(maxDelta, cmplx.Abs(delta))
+		}
+		if maxDelta <= tolerance {
+			break
+		}
+	}
+	// The Durand-Kerner method converges slowly to repeated roots, so the
+	// roots are polished with Newton's method.
+	evaluateDerivative := func(z complex128) complex128 {
+		value := 0.0 + 0i
+		for i := degree; i >= 1; i-- {
+			value = value*z + complex(float64(i), 0)*monic[i]
+		}
+		return value
+	}
+	for i := 0; i < degree; i++ {
+		residual := cmplx.Abs(evaluate(roots[i]))
+		z := roots[i]
+		for n := 0; n < maxIterations && residual > 0; n++ {
+			derivative := evaluateDerivative(z)
+			if derivative == 0 {
+				break
+			}
+			delta := evaluate(z) / derivative
+			z -= delta
+			if r := cmplx.Abs(evaluate(z)); r < residual {
+				roots[i], residual = z, r
+			}
+			if cmplx.Abs(delta) <= tolerance {
+				break
+			}
+		}
+		// a root has converged when its value is within the rounding error
+		// of evaluating the polynomial
+		bound := 0.0
+		for j := degree; j >= 0; j-- {
+			bound = bound*cmplx.Abs(roots[i]) + cmplx.Abs(monic[j])
+		}
+		if residual > ROOTS_RESIDUAL_FACTOR*float64(degree)*ROOTS_EPSILON*bound {
+			return nil, errors.New("The roots of the polynomial didn't converge")
+		}
+	}
+	return append(roots, make([]complex128, zeros)...), nil
+}
+
+// Constructs a CmplxPolynomial type whose roots are the given complex numbers.
+func CmplxPolynomialFromRoots(roots []complex128, variable rune) (CmplxPolynomial, error) {
+	if len(roots) == 0 {
+		return NIL_CMPLX_POLYNOMIAL, errors.New("At least one root is required")
+	}
+	// coefficients[i] is the coefficient of the term with power i
+	coefficients := []complex128{1}
+	for _, root := range roots {
+		product := make([]complex128, len(coefficients)+1)
+		for i, coefficient := range coefficients {
+			product[i+1] += coefficient
+			product[i] -= coefficient * root
+		}
+		coefficients = product
+	}
+	var terms []PolynomialTerm
+	for i := len(coefficients) - 1; i >= 0; i-- {
+		if coefficients[i] != 0 {
+			terms = append(terms, PolynomialTerm{Coefficient: coefficients[i], Power: i})
+		}
+	}
+	return CmplxPolynomial{Terms: terms, Variable: variable}, nil
+}
+
 // Adds a term of a polynomial to the slice of polynomial terms.
 func add_term(terms *[]PolynomialTerm, txt *[]rune, termInfo *[]int) error {
 	var err error = nil
@@ -125,7 +300,7 @@ func add_term(terms *[]PolynomialTerm, txt *[]rune, termInfo *[]int) error {
 		power *= (*termInfo)[term_pow_sign]
 	}
 	if (*termInfo)[term_pos_num_start] > -1 || (*termInfo)[term_pos_variable] > -1 {
-		*terms = append(*terms, PolynomialTerm{Coefficient: coefficient, Power: power})
+		*terms = append(*terms, PolynomialTerm{Coefficient: complex(coefficient, 0), Power: power})
 	}
 	// reset positions
 	(*termInfo)[term_num_sign] = 1
@@ -0,0 +1,70 @@
+package math
+
+import (
+	"math/cmplx"
+	"sort"
+	"testing"
+)
+
+// Creates a polynomial with real coefficients, where the key of each
+// coefficient is the power of its term.
+func testPolynomial(coefficients map[int]float64) CmplxPolynomial {
+	var terms []PolynomialTerm
+	for power, coefficient := range coefficients {
+		terms = append(terms, PolynomialTerm{Coefficient: complex(coefficient, 0), Power: power})
+	}
+	return CmplxPolynomial{Terms: terms, Variable: 'x'}
+}
+
+func TestRootsAtOrigin(t *testing.T) {
+	fromRoots, err := CmplxPolynomialFromRoots([]complex128{0, 0, 1}, 'x')
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		polynomial CmplxPolynomial
+		want       []complex128
+	}{
+		{"x^2", testPolynomial(map[int]float64{2: 1}), []complex128{0, 0}},
+		{"x^3", testPolynomial(map[int]float64{3: 1}), []complex128{0, 0, 0}},
+		{"x^3+x^2", testPolynomial(map[int]float64{3: 1, 2: 1}), []complex128{-1, 0, 0}},
+		{"x^4-x^2", testPolynomial(map[int]float64{4: 1, 2: -1}), []complex128{-1, 0, 0, 1}},
+		{"x^5-x^3", testPolynomial(map[int]float64{5: 1, 3: -1}), []complex128{-1, 0, 0, 0, 1}},
+		{"roots 0,0,1", fromRoots, []complex128{0, 0, 1}},
+	}
+	for _, test := range tests {
+		roots, err := test.polynomial.Roots(ROOTS_DEFAULT_MAX_ITERATIONS, ROOTS_DEFAULT_TOLERANCE)
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+		sort.Slice(roots, func(i, j int) bool { return real(roots[i]) < real(roots[j]) })
+		if len(roots) != len(test.want) {
+			t.Errorf("%s: got roots %v, want %v", test.name, roots, test.want)
+			continue
+		}
+		for i, root := range roots {
+			if cmplx.Abs(root-test.want[i]) > 1e-9 {
+				t.Errorf("%s: got roots %v, want %v", test.name, roots, test.want)
+				break
+			}
+		}
+	}
+}
+
+func TestRootsOfRepeatedRoots(t *testing.T) {
+	polynomial, err := CmplxPolynomialFromRoots([]complex128{1, 1, -2i}, 'x')
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots, err := polynomial.Roots(ROOTS_DEFAULT_MAX_ITERATIONS, ROOTS_DEFAULT_TOLERANCE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, root := range roots {
+		if cmplx.Abs(root-1) > 1e-6 && cmplx.Abs(root+2i) > 1e-9 {
+			t.Errorf("got roots %v, want 1, 1 and -2i", roots)
+		}
+	}
+}
@@ -0,0 +1,367 @@
+package math
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"unicode"
+)
+
+var (
+	REAL_EXPRESSION_CONSTANTS = map[string]float64{
+		"e":  math.E,
+		"pi": math.Pi,
+	}
+
+	// The functions that can be used in a real expression.
+	REAL_EXPRESSION_FUNCTIONS = map[string]func(float64) float64{
+		"sin":   math.Sin,
+		"cos":   math.Cos,
+		"tan":   math.Tan,
+		"asin":  math.Asin,
+		"acos":  math.Acos,
+		"atan":  math.Atan,
+		"sinh":  math.Sinh,
+		"cosh":  math.Cosh,
+		"tanh":  math.Tanh,
+		"exp":   math.Exp,
+		"log":   math.Log,
+		"ln":    math.Log,
+		"sqrt":  math.Sqrt,
+		"abs":   math.Abs,
+		"floor": math.Floor,
+		"ceil":  math.Ceil,
+		"round": math.Round,
+	}
+)
+
+// Represents a node of the syntax tree of a real expression.
+type realExpressionNode interface {
+	evaluate(values []float64) float64
+}
+
+// Represents a real expression of named variables, where comparisons and
+// logical operators give 1 for true and 0 for false.
+type RealExpression struct {
+	root realExpressionNode
+	// The names of the variables in the order of their values.
+	Variables []string
+}
+
+// Evaluates the value of a real expression for the given values of its
+// variables.
+func (expression *RealExpression) Evaluate(values []float64) float64 {
+	return expression.root.evaluate(values)
+}
+
+type realConstantNode struct {
+	value float64
+}
+
+type realVariableNode struct {
+	index int
+}
+
+type realUnaryNode struct {
+	operator string
+	operand  realExpressionNode
+}
+
+type realBinaryNode struct {
+	operator string
+	left     realExpressionNode
+	right    realExpressionNode
+}
+
+type realFunctionNode struct {
+	fxn      func(float64) float64
+	argument realExpressionNode
+}
+
+func (node *realConstantNode) evaluate(values []float64) float64 { return node.value }
+
+func (node *realVariableNode) evaluate(values []float64) float64 { return values[node.index] }
+
+func (node *realUnaryNode) evaluate(values []float64) float64 {
+	operand := node.operand.evaluate(values)
+	if node.operator == "!" {
+		return boolToFloat(operand == 0)
+	}
+	return -operand
+}
+
+func (node *realBinaryNode) evaluate(values []float64) float64 {
+	left := node.left.evaluate(values)
+	switch node.operator {
+	case "&&":
+		return boolToFloat(left != 0 && node.right.evaluate(values) != 0)
+	case "||":
+		return boolToFloat(left != 0 || node.right.evaluate(values) != 0)
+	}
+	right := node.right.evaluate(values)
+	switch node.operator {
+	case "+":
+		return left + right
+	case "-":
+		return left - right
+	case "*":
+		return left * right
+	case "/":
+		return left / right
+	case "%":
+		return math.Mod(left, right)
+	case "^":
+		return math.Pow(left, right)
+	case "<":
+		return boolToFloat(left < right)
+	case "<=":
+		return boolToFloat(left <= right)
+	case ">":
+		return boolToFloat(left > right)
+	case ">=":
+		return boolToFloat(left >= right)
+	case "==", "=":
+		return boolToFloat(left == right)
+	}
+	return boolToFloat(left != right)
+}
+
+func (node *realFunctionNode) evaluate(values []float64) float64 {
+	return node.fxn(node.argument.evaluate(values))
+}
+
+func boolToFloat(value bool) float64 {
+	if value {
+		return 1
+	}
+	return 0
+}
+
+// Represents the state of a real expression parser.
+type realExpressionParser struct {
+	chars     []rune
+	pos       int
+	variables []string
+}
+
+// Constructs a RealExpression type from a mathematical expression of the
+// given variables, whose names are letters, digits and underscores that
+// start with a letter.
+//
+// The operators +, -, *, /, % and ^, the comparisons <, <=, >, >=, == (or =)
+// and !=, the logical operators &&, || and ! and parentheses are supported,
+// along with the constants e and pi and the functions in
+// REAL_EXPRESSION_FUNCTIONS.
+func ParseRealExpression(txt string, variables []string) (RealExpression, error) {
+	parser := realExpressionParser{chars: []rune(txt), variables: variables}
+	root, err := parser.parseOr()
+	if err != nil {
+		return RealExpression{}, err
+	}
+	parser.skipSpaces()
+	if parser.pos < len(parser.chars) {
+		return RealExpression{}, fmt.Errorf("Invalid expression %s at position %d", txt, parser.pos+1)
+	}
+	return RealExpression{root: root, Variables: variables}, nil
+}
+
+func (parser *realExpressionParser) skipSpaces() {
+	for parser.pos < len(parser.chars) && unicode.IsSpace(parser.chars[parser.pos]) {
+		parser.pos++
+	}
+}
+
+// Consumes the first of the operators that comes next, trying longer
+// operators first.
+func (parser *realExpressionParser) nextOperator(operators ...string) string {
+	parser.skipSpaces()
+	for _, operator := range operators {
+		end := parser.pos + len(operator)
+		if end <= len(parser.chars) && string(parser.chars[parser.pos:end]) == operator {
+			parser.pos = end
+			return operator
+		}
+	}
+	return ""
+}
+
+func (parser *realExpressionParser) parseOr() (realExpressionNode, error) {
+	node, err := parser.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for parser.nextOperator("||") != "" {
+		right, err := parser.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		node = &realBinaryNode{operator: "||", left: node, right: right}
+	}
+	return node, nil
+}
+
+func (parser *realExpressionParser) parseAnd() (realExpressionNode, error) {
+	node, err := parser.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	for parser.nextOperator("&&") != "" {
+		right, err := parser.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		node = &realBinaryNode{operator: "&&", left: node, right: right}
+	}
+	return node, nil
+}
+
+func (parser *realExpressionParser) parseComparison() (realExpressionNode, error) {
+	node, err := parser.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	operator := parser.nextOperator("<=", ">=", "==", "!=", "<", ">", "=")
+	if operator == "" {
+		return node, nil
+	}
+	right, err := parser.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	return &realBinaryNode{operator: operator, left: node, right: right}, nil
+}
+
+func (parser *realExpressionParser) parseSum() (realExpressionNode, error) {
+	node, err := parser.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		operator := parser.nextOperator("+", "-")
+		if operator == "" {
+			return node, nil
+		}
+		right, err := parser.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		node = &realBinaryNode{operator: operator, left: node, right: right}
+	}
+}
+
+func (parser *realExpressionParser) parseProduct() (realExpressionNode, error) {
+	node, err := parser.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		operator := parser.nextOperator("*", "/", "%")
+		if operator == "" {
+			return node, nil
+		}
+		right, err := parser.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		node = &realBinaryNode{operator: operator, left: node, right: right}
+	}
+}
+
+func (parser *realExpressionParser) parseUnary() (realExpressionNode, error) {
+	parser.skipSpaces()
+	if parser.pos < len(parser.chars) && parser.chars[parser.pos] == '!' &&
+		(parser.pos+1 >= len(parser.chars) || parser.chars[parser.pos+1] != '=') {
+		parser.pos++
+		operand, err := parser.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &realUnaryNode{operator: "!", operand: operand}, nil
+	}
+	operator := parser.nextOperator("-", "+")
+	if operator != "" {
+		operand, err := parser.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if operator == "-" {
+			return &realUnaryNode{operator: "-", operand: operand}, nil
+		}
+		return operand, nil
+	}
+	return parser.parsePower()
+}
+
+func (parser *realExpressionParser) parsePower() (realExpressionNode, error) {
+	base, err := parser.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if parser.nextOperator("^") != "" {
+		exponent, err := parser.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &realBinaryNode{operator: "^", left: base, right: exponent}, nil
+	}
+	return base, nil
+}
+
+func (parser *realExpressionParser) parsePrimary() (realExpressionNode, error) {
+	parser.skipSpaces()
+	if parser.pos >= len(parser.chars) {
+		return nil, errors.New("Unexpected end of expression")
+	}
+	c := parser.chars[parser.pos]
+	start := parser.pos
+	if c == '(' {
+		parser.pos++
+		node, err := parser.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if parser.nextOperator(")") == "" {
+			return nil, fmt.Errorf("Missing closing parenthesis for position %d", start+1)
+		}
+		return node, nil
+	}
+	if unicode.IsDigit(c) || c == '.' {
+		for parser.pos < len(parser.chars) && (unicode.IsDigit(parser.chars[parser.pos]) || parser.chars[parser.pos] == '.') {
+			parser.pos++
+		}
+		value, err := strconv.ParseFloat(string(parser.chars[start:parser.pos]), 64)
+		if err != nil {
+			return nil, err
+		}
+		return &realConstantNode{value: value}, nil
+	}
+	if unicode.IsLetter(c) {
+		for parser.pos < len(parser.chars) && (unicode.IsLetter(parser.chars[parser.pos]) ||
+			unicode.IsDigit(parser.chars[parser.pos]) || parser.chars[parser.pos] == '_') {
+			parser.pos++
+		}
+		name := string(parser.chars[start:parser.pos])
+		for i, variable := range parser.variables {
+			if variable == name {
+				return &realVariableNode{index: i}, nil
+			}
+		}
+		if fxn, ok := REAL_EXPRESSION_FUNCTIONS[name]; ok {
+			parser.skipSpaces()
+			if parser.pos >= len(parser.chars) || parser.chars[parser.pos] != '(' {
+				return nil, fmt.Errorf("Missing argument of %s", name)
+			}
+			argument, err := parser.parsePrimary()
+			if err != nil {
+				return nil, err
+			}
+			return &realFunctionNode{fxn: fxn, argument: argument}, nil
+		}
+		if value, ok := REAL_EXPRESSION_CONSTANTS[name]; ok {
+			return &realConstantNode{value: value}, nil
+		}
+		return nil, fmt.Errorf("Unknown function or variable: %s", name)
+	}
+	return nil, fmt.Errorf("Invalid expression at position %d", start+1)
+}
@@ -0,0 +1,137 @@
+package helpers
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	"golang.org/x/image/bmp"
+	"golang.org/x/image/tiff"
+)
+
+const (
+	FORMAT_PNG    = "png"
+	FORMAT_PNG16  = "png16"
+	FORMAT_JPEG   = "jpeg"
+	FORMAT_GIF    = "gif"
+	FORMAT_BMP    = "bmp"
+	FORMAT_TIFF   = "tiff"
+	FORMAT_TIFF16 = "tiff16"
+	FORMAT_PDF    = "pdf"
+	FORMAT_SVG    = "svg"
+	FORMAT_OBJ    = "obj"
+	FORMAT_APNG   = "apng"
+	// The quality of JPEG images, from 1 to 100.
+	OUTPUT_DEFAULT_QUALITY = 90
+	// The number of colors of the palettes of GIF images, from 2 to 256.
+	OUTPUT_DEFAULT_COLORS = 256
+)
+
+var (
+	// The formats of the images of raster fractals in the order they're
+	// preferred when a request accepts several of them.
+	RASTER_FORMATS = []string{FORMAT_PNG, FORMAT_JPEG, FORMAT_GIF, FORMAT_BMP, FORMAT_TIFF, FORMAT_PNG16, FORMAT_TIFF16, FORMAT_PDF}
+	// The formats of the images of vector fractals, which are drawn with paths
+	// in SVG and PDF documents.
+	VECTOR_FORMATS = []string{FORMAT_PNG, FORMAT_SVG, FORMAT_JPEG, FORMAT_GIF, FORMAT_BMP, FORMAT_TIFF, FORMAT_PNG16, FORMAT_TIFF16, FORMAT_PDF}
+	// The formats of animations.
+	ANIMATION_FORMATS = []string{FORMAT_GIF, FORMAT_APNG}
+	// The media types of the formats.
+	FORMAT_MEDIA_TYPES = map[string]string{
+		FORMAT_PNG:    "image/png",
+		FORMAT_PNG16:  "image/png",
+		FORMAT_JPEG:   "image/jpeg",
+		FORMAT_GIF:    "image/gif",
+		FORMAT_BMP:    "image/bmp",
+		FORMAT_TIFF:   "image/tiff",
+		FORMAT_TIFF16: "image/tiff",
+		FORMAT_PDF:    "application/pdf",
+		FORMAT_SVG:    "image/svg+xml",
+		FORMAT_OBJ:    "model/obj",
+		FORMAT_APNG:   "image/apng",
+	}
+	// The file extensions of the formats.
+	FORMAT_EXTENSIONS = map[string]string{
+		FORMAT_PNG:    ".png",
+		FORMAT_PNG16:  ".png",
+		FORMAT_JPEG:   ".jpg",
+		FORMAT_GIF:    ".gif",
+		FORMAT_BMP:    ".bmp",
+		FORMAT_TIFF:   ".tiff",
+		FORMAT_TIFF16: ".tiff",
+		FORMAT_PDF:    ".pdf",
+		FORMAT_SVG:    ".svg",
+		FORMAT_OBJ:    ".obj",
+		FORMAT_APNG:   ".png",
+	}
+)
+
+// Represents the format of an image and how it's encoded.
+type OutputOptions struct {
+	Format string
+	// Specifies if the format depends on the Accept header of the request
+	// rather than on its format parameter.
+	Negotiated bool
+	// The quality of JPEG images.
+	Quality int
+	// The number of colors of the palettes of GIF images.
+	Colors int
+	// Specifies if GIF images are dithered with Floyd-Steinberg error
+	// diffusion.
+	Dither bool
+	Pdf    PdfOptions
+}
+
+// Retrieves the number of bits per channel images are rendered with for the
+// format of these options.
+func (options *OutputOptions) BitDepth() int {
+	if options.Format == FORMAT_PNG16 || options.Format == FORMAT_TIFF16 {
+		return BIT_DEPTH_16
+	}
+	return BIT_DEPTH_8
+}
+
+// Writes an image to the given output in the format of the options.
+func EncodeImage(output io.Writer, img image.Image, options OutputOptions) error {
+	switch options.Format {
+	case FORMAT_PNG:
+		return png.Encode(output, img)
+	case FORMAT_PNG16:
+		return png.Encode(output, ToRGBA64(img))
+	case FORMAT_JPEG:
+		return jpeg.Encode(output, img, &jpeg.Options{Quality: options.Quality})
+	case FORMAT_GIF:
+		var drawer draw.Drawer = draw.Src
+		if options.Dither {
+			drawer = draw.FloydSteinberg
+		}
+		return gif.Encode(output, img, &gif.Options{
+			NumColors: options.Colors,
+			Quantizer: MedianCutQuantizer{},
+			Drawer:    drawer,
+		})
+	case FORMAT_BMP:
+		return bmp.Encode(output, img)
+	case FORMAT_TIFF:
+		return tiff.Encode(output, img, &tiff.Options{Compression: tiff.Deflate, Predictor: true})
+	case FORMAT_TIFF16:
+		return tiff.Encode(output, ToRGBA64(img), &tiff.Options{Compression: tiff.Deflate, Predictor: true})
+	case FORMAT_PDF:
+		return WriteImagePdf(output, img, options.Pdf)
+	}
+	return fmt.Errorf("Unsupported image format: %s", options.Format)
+}
+
+// Converts an image to an image with 16 bits per channel.
+func ToRGBA64(img image.Image) *image.RGBA64 {
+	if img64, ok := img.(*image.RGBA64); ok {
+		return img64
+	}
+	img64 := image.NewRGBA64(img.Bounds())
+	draw.Draw(img64, img64.Bounds(), img, img.Bounds().Min, draw.Src)
+	return img64
+}
@@ -0,0 +1,36 @@
+package helpers
+
+import (
+	"sync"
+)
+
+// Calls a function for each index from 0 to count on the given number of
+// goroutines, and returns the error of the lowest index that failed.
+func ParallelFor(count, workers int, fn func(i int) error) error {
+	if workers < 1 {
+		workers = 1
+	}
+	errs := make([]error, count)
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				errs[i] = fn(i)
+			}
+		}()
+	}
+	for i := 0; i < count; i++ {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
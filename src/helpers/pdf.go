@@ -0,0 +1,88 @@
+package helpers
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+
+	"github.com/jung-kurt/gofpdf"
+	"github.com/llgcode/draw2d/draw2dkit"
+	"github.com/llgcode/draw2d/draw2dpdf"
+)
+
+const (
+	// The resolution of the points of a PDF document.
+	PDF_POINTS_PER_INCH = 72
+	PDF_DEFAULT_DPI     = 72
+	PDF_CREATOR         = "fractage"
+)
+
+// Represents the physical size and the metadata of a PDF document.
+type PdfOptions struct {
+	// The number of pixels of the image that are printed per inch.
+	DPI   float64
+	Title string
+	// The parameters the image is rendered with, such as the query of its
+	// request.
+	Parameters string
+}
+
+// Creates a PDF document with one page that fits an image of the given size
+// at the resolution of the options.
+func NewPdf(width, height int, options PdfOptions) *gofpdf.Fpdf {
+	scale := PDF_POINTS_PER_INCH / options.DPI
+	pdf := gofpdf.NewCustom(&gofpdf.InitType{
+		UnitStr: "pt",
+		Size:    gofpdf.SizeType{Wd: float64(width) * scale, Ht: float64(height) * scale},
+	})
+	pdf.SetMargins(0, 0, 0)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.SetCreator(PDF_CREATOR, true)
+	pdf.SetTitle(options.Title, true)
+	pdf.SetSubject(options.Parameters, true)
+	pdf.SetKeywords(options.Parameters, true)
+	pdf.AddPage()
+	return pdf
+}
+
+// Creates a PDF document that fits an image of the given size, whose
+// background is filled with a color, and a graphic context that draws into
+// it in pixels. The document is written with WritePdf.
+func NewPdfGraphicContext(width, height int, background color.RGBA, options PdfOptions) (*gofpdf.Fpdf, *draw2dpdf.GraphicContext) {
+	pdf := NewPdf(width, height, options)
+	gc := draw2dpdf.NewGraphicContext(pdf)
+	scale := PDF_POINTS_PER_INCH / options.DPI
+	pdf.TransformBegin()
+	gc.Scale(scale, scale)
+	gc.SetFillColor(background)
+	gc.BeginPath()
+	draw2dkit.Rectangle(gc, 0, 0, float64(width), float64(height))
+	gc.Fill()
+	return pdf, gc
+}
+
+// Writes a PDF document that's drawn with the graphic context of
+// NewPdfGraphicContext to the given output.
+func WritePdf(output io.Writer, pdf *gofpdf.Fpdf) error {
+	pdf.TransformEnd()
+	return pdf.Output(output)
+}
+
+// Writes a PDF document whose page holds an image at the resolution of the
+// options.
+func WriteImagePdf(output io.Writer, img image.Image, options PdfOptions) error {
+	var content bytes.Buffer
+	err := png.Encode(&content, img)
+	if err != nil {
+		return err
+	}
+	size := img.Bounds().Size()
+	pdf := NewPdf(size.X, size.Y, options)
+	imageOptions := gofpdf.ImageOptions{ImageType: "PNG"}
+	pdf.RegisterImageOptionsReader("image", imageOptions, &content)
+	width, height := pdf.GetPageSize()
+	pdf.ImageOptions("image", 0, 0, width, height, false, imageOptions, 0, "")
+	return pdf.Output(output)
+}
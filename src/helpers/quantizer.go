@@ -0,0 +1,143 @@
+package helpers
+
+import (
+	"image"
+	"image/color"
+	"sort"
+)
+
+const (
+	// The number of pixels the palette of images is built from.
+	QUANTIZER_MAX_SAMPLES = 1 << 20
+)
+
+// Builds the palette of an image by repeatedly splitting the box of colors
+// with the widest channel range at its median.
+type MedianCutQuantizer struct{}
+
+type colorBox struct {
+	colors []color.RGBA
+	// The channel the colors are widest along and the width of its range.
+	channel int
+	width   int
+}
+
+func newColorBox(colors []color.RGBA) *colorBox {
+	box := &colorBox{colors: colors}
+	box.channel, box.width = box.widestChannel()
+	return box
+}
+
+// Retrieves the channel this box is widest along and the width of that range.
+func (box *colorBox) widestChannel() (int, int) {
+	low := [3]uint8{255, 255, 255}
+	high := [3]uint8{0, 0, 0}
+	for _, c := range box.colors {
+		channels := [3]uint8{c.R, c.G, c.B}
+		for i, v := range channels {
+			if v < low[i] {
+				low[i] = v
+			}
+			if v > high[i] {
+				high[i] = v
+			}
+		}
+	}
+	channel, width := 0, -1
+	for i := range low {
+		if int(high[i])-int(low[i]) > width {
+			channel, width = i, int(high[i])-int(low[i])
+		}
+	}
+	return channel, width
+}
+
+// Splits this box at the median of its widest channel.
+func (box *colorBox) split() (*colorBox, *colorBox) {
+	channel := box.channel
+	sort.Slice(box.colors, func(i, j int) bool {
+		a, b := box.colors[i], box.colors[j]
+		return [3]uint8{a.R, a.G, a.B}[channel] < [3]uint8{b.R, b.G, b.B}[channel]
+	})
+	median := len(box.colors) / 2
+	return newColorBox(box.colors[:median]), newColorBox(box.colors[median:])
+}
+
+// Retrieves the average color of this box.
+func (box *colorBox) average() color.RGBA {
+	var r, g, b int
+	for _, c := range box.colors {
+		r += int(c.R)
+		g += int(c.G)
+		b += int(c.B)
+	}
+	n := len(box.colors)
+	return color.RGBA{uint8(r / n), uint8(g / n), uint8(b / n), 255}
+}
+
+// Appends the colors of the median cut of an image to a palette. A
+// transparent color is added when the image has transparent pixels.
+func (q MedianCutQuantizer) Quantize(p color.Palette, m image.Image) color.Palette {
+	return q.QuantizeImages(p, []image.Image{m})
+}
+
+// Appends the colors of the median cut of several images, such as the frames
+// of an animation, to a palette. The pixels are sampled evenly when there
+// are more than QUANTIZER_MAX_SAMPLES of them.
+func (q MedianCutQuantizer) QuantizeImages(p color.Palette, images []image.Image) color.Palette {
+	total := 0
+	for _, m := range images {
+		total += m.Bounds().Dx() * m.Bounds().Dy()
+	}
+	stride := (total + QUANTIZER_MAX_SAMPLES - 1) / QUANTIZER_MAX_SAMPLES
+	if stride < 1 {
+		stride = 1
+	}
+	colors := make([]color.RGBA, 0, total/stride+1)
+	transparent := false
+	i := 0
+	for _, m := range images {
+		bounds := m.Bounds()
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				i++
+				if i%stride != 0 {
+					continue
+				}
+				c := color.RGBAModel.Convert(m.At(x, y)).(color.RGBA)
+				if c.A == 0 {
+					transparent = true
+					continue
+				}
+				colors = append(colors, c)
+			}
+		}
+	}
+	size := cap(p) - len(p)
+	if transparent {
+		p = append(p, color.RGBA{})
+		size--
+	}
+	if len(colors) == 0 || size <= 0 {
+		return p
+	}
+	boxes := []*colorBox{newColorBox(colors)}
+	for len(boxes) < size {
+		widest, width := -1, 0
+		for i, box := range boxes {
+			if len(box.colors) > 1 && box.width > width {
+				widest, width = i, box.width
+			}
+		}
+		if widest < 0 {
+			break
+		}
+		a, b := boxes[widest].split()
+		boxes[widest] = a
+		boxes = append(boxes, b)
+	}
+	for _, box := range boxes {
+		p = append(p, box.average())
+	}
+	return p
+}
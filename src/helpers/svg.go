@@ -0,0 +1,80 @@
+package helpers
+
+import (
+	"encoding/xml"
+	"fmt"
+	"image/color"
+	"io"
+	"strings"
+
+	"github.com/llgcode/draw2d/draw2dkit"
+	"github.com/llgcode/draw2d/draw2dsvg"
+)
+
+// Creates an SVG document of the given size whose background is filled with
+// a color, and a graphic context that draws into it.
+func NewSvgGraphicContext(width, height int, background color.RGBA) (*draw2dsvg.Svg, *draw2dsvg.GraphicContext) {
+	svg := draw2dsvg.NewSvg()
+	svg.Width = fmt.Sprint(width)
+	svg.Height = fmt.Sprint(height)
+	svg.ViewBox = fmt.Sprintf("0 0 %d %d", width, height)
+	gc := draw2dsvg.NewGraphicContext(svg)
+	gc.SetFillColor(background)
+	gc.BeginPath()
+	draw2dkit.Rectangle(gc, 0, 0, float64(width), float64(height))
+	gc.Fill()
+	return svg, gc
+}
+
+// Writes an SVG document to the given output after merging its adjacent
+// groups that are drawn the same way.
+func WriteSvg(output io.Writer, svg *draw2dsvg.Svg) error {
+	svg.Groups = MergeSvgGroups(svg.Groups)
+	_, err := io.WriteString(output, xml.Header)
+	if err != nil {
+		return err
+	}
+	return xml.NewEncoder(output).Encode(svg)
+}
+
+// Merges the adjacent groups of paths that have the same fill, stroke and
+// transform into one group. The paths of merged groups that are only
+// stroked with an opaque color are joined into one path, which continues
+// the previous path when it starts where that path ends.
+func MergeSvgGroups(groups []*draw2dsvg.Group) []*draw2dsvg.Group {
+	merged := make([]*draw2dsvg.Group, 0, len(groups))
+	for _, group := range groups {
+		if len(merged) == 0 || !canMergeSvgGroups(merged[len(merged)-1], group) {
+			merged = append(merged, group)
+			continue
+		}
+		last := merged[len(merged)-1]
+		for _, path := range group.Paths {
+			n := len(last.Paths)
+			if n > 0 && last.Fill == "" && strings.HasPrefix(last.Stroke, "#") && last.Paths[n-1].FillStroke == path.FillStroke {
+				last.Paths[n-1].Desc = joinSvgPathDescs(last.Paths[n-1].Desc, path.Desc)
+			} else {
+				last.Paths = append(last.Paths, path)
+			}
+		}
+	}
+	return merged
+}
+
+func canMergeSvgGroups(a, b *draw2dsvg.Group) bool {
+	return a.FillStroke == b.FillStroke && a.Transform == b.Transform && a.Mask == b.Mask &&
+		len(a.Groups) == 0 && len(b.Groups) == 0 && len(a.Texts) == 0 && len(b.Texts) == 0 &&
+		a.Image == nil && b.Image == nil
+}
+
+// Joins two path descriptions, leaving out the first move of the second
+// description when it moves to the end of the first description.
+func joinSvgPathDescs(a, b string) string {
+	if strings.HasPrefix(b, "M ") {
+		end := strings.IndexByte(b[2:], ' ') + 2
+		if end > 2 && strings.HasSuffix(a, " "+b[2:end]) && !strings.HasSuffix(a, "Z") {
+			return a + b[end:]
+		}
+	}
+	return a + " " + b
+}
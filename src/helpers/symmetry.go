@@ -0,0 +1,80 @@
+package helpers
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	SYMMETRY_MAX_ORDER = 64
+	SYMMETRY_NONE      = "none"
+)
+
+// Represents a cyclic (Cn) or dihedral (Dn) symmetry group of the plane.
+type Symmetry struct {
+	// The number of rotations of the group.
+	Order int
+	// Whether the group also has a reflection for each rotation.
+	Dihedral bool
+	matrices [][4]float64
+}
+
+// Parses a symmetry group given as C or D followed by its order, such as C6
+// or D4.
+func ParseSymmetry(txt string) (Symmetry, error) {
+	text := strings.ToUpper(strings.Trim(txt, WHITESPACE_CUTSET))
+	if text == "" || text == strings.ToUpper(SYMMETRY_NONE) {
+		return Symmetry{Order: 1}, nil
+	}
+	order, err := strconv.Atoi(text[1:])
+	if (text[0] != 'C' && text[0] != 'D') || err != nil {
+		return Symmetry{}, fmt.Errorf("Invalid symmetry: %s. Expected Cn or Dn", txt)
+	}
+	if order < 1 || order > SYMMETRY_MAX_ORDER {
+		return Symmetry{}, fmt.Errorf("The order of the symmetry must be between 1 and %d", SYMMETRY_MAX_ORDER)
+	}
+	return Symmetry{Order: order, Dihedral: text[0] == 'D'}, nil
+}
+
+// Checks if the group has any element besides the identity.
+func (symmetry *Symmetry) IsTrivial() bool {
+	return symmetry.Order <= 1 && !symmetry.Dihedral
+}
+
+// Retrieves the linear parts a, b, c and d of the rotations and reflections
+// of the group, where x = a*x + b*y and y = c*x + d*y. The identity comes
+// first.
+func (symmetry *Symmetry) Matrices() [][4]float64 {
+	if symmetry.matrices != nil {
+		return symmetry.matrices
+	}
+	order := symmetry.Order
+	if order < 1 {
+		order = 1
+	}
+	symmetry.matrices = make([][4]float64, 0, 2*order)
+	for k := 0; k < order; k++ {
+		sin, cos := math.Sincos(2 * math.Pi * float64(k) / float64(order))
+		symmetry.matrices = append(symmetry.matrices, [4]float64{cos, -sin, sin, cos})
+	}
+	if symmetry.Dihedral {
+		// reflections across the lines through the center at multiples of
+		// half the angle of the rotations
+		for k := 0; k < order; k++ {
+			sin, cos := math.Sincos(2 * math.Pi * float64(k) / float64(order))
+			symmetry.matrices = append(symmetry.matrices, [4]float64{cos, sin, sin, -cos})
+		}
+	}
+	return symmetry.matrices
+}
+
+// Passes the images of a point under each element of the group around a
+// center to the plot function.
+func (symmetry *Symmetry) Images(x, y float64, center Point, plot func(x, y float64)) {
+	dx, dy := x-center.X, y-center.Y
+	for _, m := range symmetry.Matrices() {
+		plot(center.X+m[0]*dx+m[1]*dy, center.Y+m[2]*dx+m[3]*dy)
+	}
+}